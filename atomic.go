@@ -0,0 +1,193 @@
+package maker
+
+import (
+	"os"
+	"path"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+)
+
+// writeFileAtomic replaces the file contents by writing them to a temporary
+// file on the same directory, flushing it to the storage media if supported,
+// and renaming it over the original file, flushing the directory as well so
+// the rename survives a crash. Readers either see the previous contents or the
+// new ones, but never a partial write. Existing files keep their permissions,
+// while new ones are created with perm
+func writeFileAtomic(filesystem billy.Filesystem, filename string, data []byte, perm os.FileMode) (err error) {
+	dir := path.Dir(filename)
+
+	err = filesystem.MkdirAll(dir, 0750)
+	if err != nil {
+		return err
+	}
+
+	mode := perm
+	info, err := filesystem.Stat(filename)
+	if err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	fd, err := filesystem.TempFile(dir, "."+path.Base(filename)+".")
+	if err != nil {
+		return err
+	}
+
+	tempName := fd.Name()
+	defer func() {
+		if err != nil {
+			_ = filesystem.Remove(tempName)
+		}
+	}()
+
+	_, err = fd.Write(data)
+	if err == nil {
+		err = syncFile(fd)
+	}
+
+	closeErr := fd.Close()
+	if err != nil {
+		return err
+	}
+
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if change, ok := filesystem.(chmoder); ok {
+		err = change.Chmod(tempName, mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = filesystem.Rename(tempName, filename)
+	if err != nil {
+		return err
+	}
+
+	if syncer, ok := filesystem.(dirSyncer); ok {
+		return syncer.SyncDir(dir)
+	}
+
+	return nil
+}
+
+// chmoder represents a filesystem that supports changing file permissions
+type chmoder interface {
+	Chmod(name string, mode os.FileMode) error
+}
+
+// dirSyncer represents a filesystem that supports flushing the entries of a
+// directory to the storage media
+type dirSyncer interface {
+	SyncDir(dir string) error
+}
+
+// syncFile flushes the file contents to the storage media, if supported
+func syncFile(fd billy.File) error {
+	syncer, ok := fd.(Syncable)
+	if !ok {
+		return nil
+	}
+
+	return syncer.Sync()
+}
+
+// atomicFile is a File whose contents are replaced atomically. Replacing it
+// swaps the underlying descriptor, so concurrent processes must be serialized
+// with a ProcessLock instead of locking the file itself
+type atomicFile struct {
+	billy.File
+	filesystem billy.Filesystem
+	filename   string
+}
+
+// openAtomicFile opens a file for reading and atomic replacement, creating it
+// if needed
+func openAtomicFile(filesystem billy.Filesystem, filename string) (*atomicFile, error) {
+	fd, err := filesystem.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomicFile{
+		File:       fd,
+		filesystem: filesystem,
+		filename:   filename,
+	}, nil
+}
+
+// Replace implements Replaceable
+func (file *atomicFile) Replace(data []byte) error {
+	err := writeFileAtomic(file.filesystem, file.filename, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	fd, err := file.filesystem.OpenFile(file.filename, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	previous := file.File
+	file.File = fd
+
+	return previous.Close()
+}
+
+// osFilesystem is an OS filesystem rooted on a directory, whose temporary
+// files support flushing their contents to the storage media
+type osFilesystem struct {
+	billy.Filesystem
+}
+
+func newOSFilesystem(dir string) billy.Filesystem {
+	return &osFilesystem{osfs.New(dir)}
+}
+
+// TempFile implements billy.TempFile
+func (filesystem *osFilesystem) TempFile(dir, prefix string) (billy.File, error) {
+	fd, err := filesystem.Filesystem.TempFile(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &osFile{
+		File:     fd,
+		fullpath: filesystem.Join(filesystem.Root(), fd.Name()),
+	}, nil
+}
+
+// Chmod changes the permissions of a file
+func (filesystem *osFilesystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(filesystem.Join(filesystem.Root(), name), mode)
+}
+
+// SyncDir flushes the entries of a directory to the storage media
+func (filesystem *osFilesystem) SyncDir(dir string) error {
+	return syncDir(filesystem.Join(filesystem.Root(), dir))
+}
+
+// Chroot implements billy.Chroot
+func (filesystem *osFilesystem) Chroot(dir string) (billy.Filesystem, error) {
+	return newOSFilesystem(filesystem.Join(filesystem.Root(), dir)), nil
+}
+
+// osFile is an OS file that supports flushing its contents to the storage media
+type osFile struct {
+	billy.File
+	fullpath string
+}
+
+// Sync implements Syncable. Flushing through another descriptor is enough, as
+// it applies to the file itself
+func (file *osFile) Sync() error {
+	fd, err := os.OpenFile(file.fullpath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	return fd.Sync()
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package maker
+
+import "os"
+
+// syncDir flushes the directory entries, such as renamed files, to the storage
+// media
+func syncDir(dir string) error {
+	fd, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	return fd.Sync()
+}
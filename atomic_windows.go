@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package maker
+
+// syncDir does nothing, as directories cannot be flushed on Windows, where
+// renames are persisted along with the file metadata
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,403 @@
+package maker
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// AuthMethod identifies how to authenticate against a repository remote
+type AuthMethod string
+
+const (
+	// AuthAuto uses the SSH agent for SSH remotes, and the netrc file entry of
+	// the host for HTTP(S) remotes, if any
+	AuthAuto AuthMethod = ""
+	// AuthNone disables authentication
+	AuthNone AuthMethod = "none"
+	// AuthSSHAgent authenticates with the keys of the running SSH agent
+	AuthSSHAgent AuthMethod = "ssh-agent"
+	// AuthSSHKey authenticates with a private key file
+	AuthSSHKey AuthMethod = "ssh-key"
+	// AuthBasic authenticates over HTTP(S) with an username and password
+	AuthBasic AuthMethod = "basic"
+	// AuthToken authenticates over HTTP(S) with an access token, sent as the
+	// basic authentication password
+	AuthToken AuthMethod = "token"
+	// AuthNetrc authenticates over HTTP(S) with the netrc file entry of the host
+	AuthNetrc AuthMethod = "netrc"
+	// AuthCredentialHelper authenticates over HTTP(S) with the credentials
+	// returned by the git credential helpers, through `git credential fill`
+	AuthCredentialHelper AuthMethod = "credential-helper"
+)
+
+// Auth configures how to authenticate against a repository remote. Secrets are
+// never stored on it, only references to where they are found, such as
+// environment variable names and file paths
+type Auth struct {
+	Method AuthMethod `yaml:"method,omitempty"`
+	// User is the SSH or HTTP(S) username, if not taken from UserEnv
+	User string `yaml:"user,omitempty"`
+	// UserEnv is the environment variable with the HTTP(S) username
+	UserEnv string `yaml:"userEnv,omitempty"`
+	// PasswordEnv is the environment variable with the HTTP(S) password
+	PasswordEnv string `yaml:"passwordEnv,omitempty"`
+	// TokenEnv is the environment variable with the HTTP(S) access token
+	TokenEnv string `yaml:"tokenEnv,omitempty"`
+	// KeyFile is the SSH private key file path, which may start with ~/
+	KeyFile string `yaml:"keyFile,omitempty"`
+	// PassphraseEnv is the environment variable with the SSH private key
+	// passphrase, if encrypted
+	PassphraseEnv string `yaml:"passphraseEnv,omitempty"`
+}
+
+// defaultTokenUser is the username sent along access tokens, which most git
+// hosts ignore
+const defaultTokenUser = "git"
+
+// TransportAuth returns the go-git authentication for the repository URL, or
+// nil if it needs none
+func (auth *Auth) TransportAuth(url string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+
+	method := AuthAuto
+	if auth != nil {
+		method = auth.Method
+	}
+
+	switch method {
+	case AuthAuto:
+		return autoAuth(endpoint)
+	case AuthNone:
+		return nil, nil
+	case AuthSSHAgent:
+		return ssh.NewSSHAgentAuth(auth.sshUser(endpoint))
+	case AuthSSHKey:
+		if auth.KeyFile == "" {
+			return nil, fmt.Errorf("%s auth requires a key file", method)
+		}
+
+		keyFile, err := expandHome(auth.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		passphrase, err := optionalEnv(auth.PassphraseEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		return ssh.NewPublicKeysFromFile(auth.sshUser(endpoint), keyFile, passphrase)
+	case AuthBasic:
+		user := auth.User
+		if auth.UserEnv != "" {
+			user, err = requiredEnv(auth.UserEnv)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		password, err := requiredEnv(auth.PasswordEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		return &githttp.BasicAuth{Username: user, Password: password}, nil
+	case AuthToken:
+		token, err := requiredEnv(auth.TokenEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		user := auth.User
+		if user == "" {
+			user = defaultTokenUser
+		}
+
+		return &githttp.BasicAuth{Username: user, Password: token}, nil
+	case AuthNetrc:
+		basicAuth, err := netrcAuth(endpoint.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		if basicAuth == nil {
+			return nil, fmt.Errorf("no netrc entry found for %s", endpoint.Host)
+		}
+
+		return basicAuth, nil
+	case AuthCredentialHelper:
+		return credentialHelperAuth(endpoint)
+	default:
+		return nil, fmt.Errorf("unknown auth method %s", method)
+	}
+}
+
+// sshUser returns the configured SSH user, or the URL one
+func (auth *Auth) sshUser(endpoint *transport.Endpoint) string {
+	if auth.User != "" {
+		return auth.User
+	}
+
+	if endpoint.User != "" {
+		return endpoint.User
+	}
+
+	return ssh.DefaultUsername
+}
+
+// autoAuth returns the authentication that needs no configuration: the SSH
+// agent for SSH remotes, and the host netrc entry for HTTP(S) ones
+func autoAuth(endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	switch endpoint.Protocol {
+	case "ssh":
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			return nil, nil
+		}
+
+		user := endpoint.User
+		if user == "" {
+			user = ssh.DefaultUsername
+		}
+
+		return ssh.NewSSHAgentAuth(user)
+	case "http", "https":
+		if endpoint.User != "" || endpoint.Password != "" {
+			return &githttp.BasicAuth{Username: endpoint.User, Password: endpoint.Password}, nil
+		}
+
+		basicAuth, err := netrcAuth(endpoint.Host)
+		if err != nil || basicAuth == nil {
+			return nil, err
+		}
+
+		return basicAuth, nil
+	default:
+		return nil, nil
+	}
+}
+
+// netrcAuth returns the credentials of the host on the user netrc file, or nil
+// if there's no entry for it
+func netrcAuth(host string) (*githttp.BasicAuth, error) {
+	filename, err := netrcFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	login, password, found := parseNetrc(data, host)
+	if !found {
+		return nil, nil
+	}
+
+	return &githttp.BasicAuth{Username: login, Password: password}, nil
+}
+
+// netrcFilename returns the user netrc file path, from the NETRC environment
+// variable or the home directory
+func netrcFilename() (string, error) {
+	if filename := os.Getenv("NETRC"); filename != "" {
+		return filename, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc"), nil
+	}
+
+	return filepath.Join(home, ".netrc"), nil
+}
+
+// parseNetrc returns the login and password of the machine entry for the host,
+// or the default entry if there's none
+func parseNetrc(data []byte, host string) (login, password string, found bool) {
+	type entry struct {
+		login, password string
+	}
+
+	var (
+		current      *entry
+		defaultEntry *entry
+		hostEntry    *entry
+	)
+
+	fields := strings.Fields(string(data))
+
+	for index := 0; index < len(fields); index++ {
+		field := fields[index]
+
+		next := func() string {
+			if index+1 >= len(fields) {
+				return ""
+			}
+
+			index++
+
+			return fields[index]
+		}
+
+		switch field {
+		case "machine":
+			current = &entry{}
+			if next() == host && hostEntry == nil {
+				hostEntry = current
+			}
+		case "default":
+			current = &entry{}
+			defaultEntry = current
+		case "login":
+			value := next()
+			if current != nil {
+				current.login = value
+			}
+		case "password":
+			value := next()
+			if current != nil {
+				current.password = value
+			}
+		case "account":
+			next()
+		case "macdef":
+			// macros run until the next empty line, which fields do not keep
+			current = nil
+		}
+	}
+
+	if hostEntry == nil {
+		hostEntry = defaultEntry
+	}
+
+	if hostEntry == nil {
+		return "", "", false
+	}
+
+	return hostEntry.login, hostEntry.password, true
+}
+
+// credentialHelperAuth returns the credentials that the git credential helpers
+// configured for the user provide for the endpoint
+func credentialHelperAuth(endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	var request bytes.Buffer
+
+	fmt.Fprintf(&request, "protocol=%s\n", endpoint.Protocol)
+	fmt.Fprintf(&request, "host=%s\n", endpointHost(endpoint))
+	fmt.Fprintf(&request, "path=%s\n", strings.TrimPrefix(endpoint.Path, "/"))
+
+	if endpoint.User != "" {
+		fmt.Fprintf(&request, "username=%s\n", endpoint.User)
+	}
+
+	request.WriteString("\n")
+
+	command := exec.Command("git", "credential", "fill")
+	command.Stdin = &request
+	// never prompt, as maker may run unattended
+	command.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	// the helpers diagnostics are captured on the error instead of the output
+	output, err := command.Output()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+		return nil, fmt.Errorf("git credential fill: %w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("git credential fill: %w", err)
+	}
+
+	basicAuth := &githttp.BasicAuth{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "username":
+			basicAuth.Username = value
+		case "password":
+			basicAuth.Password = value
+		}
+	}
+
+	if basicAuth.Password == "" {
+		return nil, fmt.Errorf("git credential helpers returned no password for %s", endpoint.Host)
+	}
+
+	return basicAuth, nil
+}
+
+// endpointHost returns the endpoint host, with the port if not the default one
+func endpointHost(endpoint *transport.Endpoint) string {
+	if endpoint.Port == 0 {
+		return endpoint.Host
+	}
+
+	return fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port)
+}
+
+// requiredEnv returns the value of a non-empty environment variable
+func requiredEnv(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("missing environment variable name")
+	}
+
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
+
+// optionalEnv returns the value of an environment variable, if named
+func optionalEnv(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+
+	return requiredEnv(name)
+}
+
+// expandHome replaces a leading ~/ on the path with the user home directory
+func expandHome(filename string) (string, error) {
+	if !strings.HasPrefix(filename, "~/") {
+		return filename, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, filename[2:]), nil
+}
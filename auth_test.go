@@ -0,0 +1,217 @@
+package maker_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/wwmoraes/maker"
+)
+
+// gitHTTPBackend returns the path of the git smart HTTP server, skipping the
+// test if it is not available
+func gitHTTPBackend(t *testing.T) string {
+	t.Helper()
+
+	output, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Skipf("git not available: %v", err)
+	}
+
+	backendPath := filepath.Join(strings.TrimSpace(string(output)), "git-http-backend")
+	if _, err := os.Stat(backendPath); err != nil {
+		t.Skip("git-http-backend not available")
+	}
+
+	return backendPath
+}
+
+// privateServer serves an on-disk snippets repository through the git smart
+// HTTP protocol, accepting only requests with the given basic credentials
+func privateServer(t *testing.T, username, password string) string {
+	t.Helper()
+
+	backendPath := gitHTTPBackend(t)
+
+	root := t.TempDir()
+
+	repo, err := git.PlainInit(filepath.Join(root, "snippets"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, filepath.Join(root, "snippets", "snippets", "go.mk"), "private\n")
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = worktree.Add("snippets/go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := worktree.Commit("1.0.0", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.CreateTag("1.0.0", hash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &cgi.Handler{
+		Path: backendPath,
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != username || pass != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="snippets"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL + "/snippets/.git"
+}
+
+func addPrivateSnippet(t *testing.T, config string) (*maker.MemoryFile, error) {
+	t.Helper()
+
+	conf := maker.NewMemoryFile([]byte(config))
+
+	mk, err := maker.New(conf, maker.NewMemoryFile(nil), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mk.Close()
+
+	return conf, mk.Add("private:go@1.0.0")
+}
+
+func TestAuthToken(t *testing.T) {
+	url := privateServer(t, "git", "s3cr3t-token")
+
+	config := "repositories:\n- alias: private\n  url: " + url + "\n  auth:\n    method: token\n    tokenEnv: MAKER_TEST_TOKEN\n  snippets: {}\n"
+
+	t.Setenv("MAKER_TEST_TOKEN", "")
+
+	_, err := addPrivateSnippet(t, config)
+	if err == nil || !strings.Contains(err.Error(), "MAKER_TEST_TOKEN") {
+		t.Errorf("got error %v, want unset variable error", err)
+	}
+
+	t.Setenv("MAKER_TEST_TOKEN", "wrong")
+
+	_, err = addPrivateSnippet(t, config)
+	if err == nil {
+		t.Error("got no error with a wrong token")
+	}
+
+	t.Setenv("MAKER_TEST_TOKEN", "s3cr3t-token")
+
+	conf, err := addPrivateSnippet(t, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(conf.Bytes(), []byte("s3cr3t-token")) {
+		t.Errorf("configuration stores the token:\n%s", conf.Bytes())
+	}
+
+	if !bytes.Contains(conf.Bytes(), []byte("tokenEnv: MAKER_TEST_TOKEN")) {
+		t.Errorf("configuration lost the auth reference:\n%s", conf.Bytes())
+	}
+}
+
+func TestAuthBasic(t *testing.T) {
+	url := privateServer(t, "alice", "p4ssw0rd")
+
+	t.Setenv("MAKER_TEST_PASSWORD", "p4ssw0rd")
+
+	_, err := addPrivateSnippet(t, "repositories:\n- alias: private\n  url: "+url+"\n  auth:\n    method: basic\n    user: alice\n    passwordEnv: MAKER_TEST_PASSWORD\n  snippets: {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthNetrc(t *testing.T) {
+	url := privateServer(t, "bob", "n3trc")
+
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	writeTestFile(t, netrc, "machine example.com login nobody password nothing\nmachine 127.0.0.1\n  login bob\n  password n3trc\n")
+	t.Setenv("NETRC", netrc)
+
+	config := "repositories:\n- alias: private\n  url: " + url + "\n  snippets: {}\n"
+
+	_, err := addPrivateSnippet(t, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, netrc, "default login bob password wrong\n")
+
+	_, err = addPrivateSnippet(t, config)
+	if err == nil {
+		t.Error("got no error with wrong netrc credentials")
+	}
+}
+
+func TestAuthUnknownMethod(t *testing.T) {
+	auth := &maker.Auth{Method: "magic"}
+
+	_, err := auth.TransportAuth("https://example.com/snippets.git")
+	if err == nil {
+		t.Error("got no error for an unknown auth method")
+	}
+}
+
+func TestAuthCredentialHelper(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	auth := &maker.Auth{Method: maker.AuthCredentialHelper}
+
+	// the helpers diagnostics are part of the error
+	_, err := auth.TransportAuth("https://example.com/snippets.git")
+	if err == nil || !strings.Contains(err.Error(), "terminal prompts disabled") {
+		t.Errorf("got error %v, want the git diagnostics", err)
+	}
+
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "credential.helper")
+	t.Setenv("GIT_CONFIG_VALUE_0", "!f() { echo username=ci; echo password=s3cr3t; }; f")
+
+	transportAuth, err := auth.TransportAuth("https://example.com/snippets.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(transportAuth.String(), "ci:") {
+		t.Errorf("got auth %s, want the helper credentials", transportAuth)
+	}
+}
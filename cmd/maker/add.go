@@ -5,9 +5,9 @@ import (
 )
 
 var addCmd = &cobra.Command{
-	Use:   "add",
+	Use:   "add [alias:]name[@version|@branch:name|@tag:name|@commit:hash]",
 	Short: "add a snippet",
-	Long:  "fetches a snippet file and adds it as a dependency",
+	Long:  "fetches a snippet file and adds it as a dependency, pinned to a version constraint (default *), branch, tag or commit",
 	RunE:  addRun,
 	Args:  cobra.ExactArgs(1),
 }
@@ -17,7 +17,12 @@ func init() {
 }
 
 func addRun(cmd *cobra.Command, args []string) (err error) {
-	err = mk.Add(args[0])
+	err = requireProject()
+	if err != nil {
+		return err
+	}
+
+	err = mk.AddContext(cmd.Context(), args[0])
 	if err != nil {
 		return err
 	}
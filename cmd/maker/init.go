@@ -17,5 +17,9 @@ func init() {
 }
 
 func initRun(cmd *cobra.Command, args []string) error {
-	return mk.Init()
+	if err := requireProject(); err != nil {
+		return err
+	}
+
+	return mk.InitContext(cmd.Context())
 }
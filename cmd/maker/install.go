@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/wwmoraes/maker"
 )
 
 var (
@@ -9,18 +10,43 @@ var (
 		Use:   "install",
 		Short: "installs all snippets",
 		Long:  "fetches all snippet files listed as dependency",
-		RunE:  updateRun,
+		RunE:  installRun,
 	}
-	installForce bool
+	installForce          bool
+	installFrozenLockfile bool
+	installStrategy       string
 )
 
 func init() {
 	rootCmd.AddCommand(installCmd)
 	installCmd.Flags().BoolVarP(&installForce, "force", "f", false, "ignores the lock file, and checks the files directly")
+	installCmd.Flags().BoolVar(&installFrozenLockfile, "frozen-lockfile", false, "fails if the lock file is out of sync with the configuration, and never writes to either")
+	installCmd.Flags().StringVar(&installStrategy, "strategy", string(maker.StrategyMerge), "how to handle local snippet changes: ours, theirs, merge or fail")
 }
 
-func updateRun(cmd *cobra.Command, args []string) (err error) {
-	err = mk.Install(installForce)
+func installRun(cmd *cobra.Command, args []string) (err error) {
+	strategy, err := maker.ParseMergeStrategy(installStrategy)
+	if err != nil {
+		return err
+	}
+
+	if workspaceMode() {
+		if installFrozenLockfile {
+			err = ws.InstallFrozenContext(cmd.Context(), strategy)
+		} else {
+			err = ws.InstallContext(cmd.Context(), installForce, strategy)
+		}
+
+		printDivergences()
+
+		return err
+	}
+
+	if installFrozenLockfile {
+		return mk.InstallFrozenContext(cmd.Context(), strategy)
+	}
+
+	err = mk.InstallContext(cmd.Context(), installForce, strategy)
 	if err != nil {
 		return err
 	}
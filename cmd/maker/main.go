@@ -1,8 +1,20 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	"github.com/wwmoraes/maker"
 )
@@ -17,17 +29,247 @@ var rootCmd = &cobra.Command{
 }
 
 var (
-	mk *maker.Maker
+	mk          *maker.Maker
+	ws          *maker.Workspace
+	lockTimeout time.Duration
+	verbose     bool
+	quiet       bool
+	logFormat   string
+	projectDir  string
+	configFile  string
+	lockFile    string
+	snippetsDir string
+	offline     bool
+	cacheDir    string
+	colorMode   string
+	jobs        int
+	// output receives the user-facing messages, while diagnostics are logged
+	// to stderr
+	output io.Writer = os.Stdout
 )
 
+func init() {
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", maker.DefaultLockTimeout, "how long to wait for other maker processes on the same project")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "logs debug diagnostics")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "prints errors only")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "diagnostics format, either text or json")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "how many repositories are fetched at the same time")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", "auto", "colors the output, either auto, always or never")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", os.Getenv("MAKER_CACHE_DIR"), "keeps git repositories across runs on the directory (env MAKER_CACHE_DIR)")
+	rootCmd.PersistentFlags().StringVarP(&projectDir, "dir", "C", os.Getenv("MAKER_DIR"), "project directory, instead of the current one (env MAKER_DIR)")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", envOr("MAKER_CONFIG", maker.ConfFilename), "configuration file, relative to the project directory (env MAKER_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&lockFile, "lock", envOr("MAKER_LOCK", maker.LockFilename), "lock file, relative to the project directory (env MAKER_LOCK)")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", envBool("MAKER_OFFLINE"), "resolves remote repositories from the vendor directory only (env MAKER_OFFLINE)")
+	rootCmd.PersistentFlags().StringVar(&snippetsDir, "snippets-dir", envOr("MAKER_SNIPPETS_DIR", maker.SnippetsDirectory), "snippets directory, relative to the project directory (env MAKER_SNIPPETS_DIR)")
+}
+
+// envOr returns the environment variable value, or the fallback if it is unset
+// or empty
+func envOr(name, fallback string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+// envBool returns true if the environment variable is set to a true value, such
+// as 1 or true
+func envBool(name string) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+
+	return err == nil && value
+}
+
 func preRun(cmd *cobra.Command, args []string) (err error) {
-	mk, err = maker.NewDefault()
+	userConfig, err := maker.LoadUserConfig()
+	if err != nil {
+		return err
+	}
+
+	// the user preferences apply unless overridden by flags
+	if !cmd.Flags().Changed("log-format") && userConfig.Output.LogFormat != "" {
+		logFormat = userConfig.Output.LogFormat
+	}
+
+	if !cmd.Flags().Changed("color") && userConfig.Output.Color != "" {
+		colorMode = userConfig.Output.Color
+	}
+
+	if jobs < 1 {
+		return fmt.Errorf("--jobs must be at least 1")
+	}
+
+	err = setColorMode()
+	if err != nil {
+		return err
+	}
+
+	logger, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	if quiet {
+		output = io.Discard
+	}
+
+	opts := []maker.Option{
+		maker.WithLogger(logger),
+		maker.WithUserConfig(userConfig),
+		maker.WithLockTimeout(lockTimeout),
+		maker.WithConfigFilename(configFile),
+		maker.WithLockFilename(lockFile),
+		maker.WithSnippetsDirectory(snippetsDir),
+		maker.WithOffline(offline),
+		maker.WithCacheDirectory(cacheDir),
+		maker.WithJobs(jobs),
+	}
+
+	if !quiet {
+		opts = append(opts, maker.WithSubscriber(maker.SubscriberFunc(printEvent)))
+
+		if isatty.IsTerminal(os.Stderr.Fd()) {
+			opts = append(opts, maker.WithSubscriber(&progressBar{writer: os.Stderr, width: 30}))
+		}
+	}
+
+	dir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return err
+	}
+
+	root, found, err := maker.FindWorkspace(dir)
+	if err != nil {
+		return err
+	}
+
+	if found {
+		return openWorkspace(root, dir, opts)
+	}
+
+	mk, err = maker.NewDefault(append(opts, maker.WithRoot(dir))...)
 
 	return err
 }
 
+// openWorkspace opens the whole workspace on its root, or on members that use
+// a shared lock, and only the project on the directory otherwise, so other
+// members do not interfere with it
+func openWorkspace(root, dir string, opts []maker.Option) (err error) {
+	relDir, err := filepath.Rel(root, dir)
+	if err != nil {
+		return err
+	}
+
+	if root != dir {
+		config, err := maker.LoadWorkspaceConfig(root)
+		if err != nil {
+			return err
+		}
+
+		if !config.SharedLock || !config.HasMember(relDir) {
+			mk, err = maker.NewDefault(append(opts, maker.WithRoot(dir))...)
+			return err
+		}
+	}
+
+	ws, err = maker.OpenWorkspace(root, opts...)
+	if err != nil || root == dir {
+		return err
+	}
+
+	// members of workspaces with a shared lock must use it
+	member, found := ws.Member(relDir)
+	if !found {
+		return fmt.Errorf("%s is not a member of the workspace on %s", relDir, root)
+	}
+
+	mk = member
+
+	return nil
+}
+
+// requireProject fails if maker runs on a workspace root instead of a project
+func requireProject() error {
+	if mk == nil {
+		return fmt.Errorf("%s is a workspace root, run this command on a member with --dir", ws.Root)
+	}
+
+	return nil
+}
+
+// workspaceMode returns true if maker runs on a workspace root
+func workspaceMode() bool {
+	return mk == nil && ws != nil
+}
+
+// setColorMode enables or disables the output colors as set by the flag
+func setColorMode() error {
+	switch colorMode {
+	case "auto":
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	default:
+		return fmt.Errorf("unknown color mode %s, expected auto, always or never", colorMode)
+	}
+
+	return nil
+}
+
+// newLogger returns a stderr logger with the level and format set by the flags
+func newLogger() (*slog.Logger, error) {
+	if verbose && quiet {
+		return nil, fmt.Errorf("--verbose and --quiet are mutually exclusive")
+	}
+
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+
+	if quiet {
+		level = slog.LevelError
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+
+	switch logFormat {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, handlerOptions)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, handlerOptions)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %s, expected text or json", logFormat)
+	}
+}
+
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// interrupting stops the command without writing the config and lock files
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	err := rootCmd.ExecuteContext(ctx)
+
+	stop()
+
+	if ws != nil {
+		closeErr := ws.Close()
+		if closeErr != nil {
+			fmt.Fprintln(os.Stderr, "Error:", closeErr)
+			err = closeErr
+		}
+	} else if mk != nil {
+		closeErr := mk.Close()
+		if closeErr != nil {
+			fmt.Fprintln(os.Stderr, "Error:", closeErr)
+			err = closeErr
+		}
+	}
+
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TestMain runs maker itself when requested by a test, as the environment
+// variables are read when the flags are defined
+func TestMain(m *testing.M) {
+	if os.Getenv("MAKER_TEST_MAIN") == "1" {
+		os.Args = append([]string{"maker"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// execMaker runs maker on the working directory with the arguments and extra
+// environment variables, returning its output
+func execMaker(t *testing.T, dir string, env []string, args ...string) ([]byte, error) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "MAKER_TEST_MAIN=1", "MAKER_DIR=", "MAKER_CONFIG=", "MAKER_LOCK=", "MAKER_SNIPPETS_DIR=")
+	cmd.Env = append(cmd.Env, env...)
+
+	return cmd.CombinedOutput()
+}
+
+// runMaker runs maker like execMaker, failing the test if it does not succeed
+func runMaker(t *testing.T, dir string, env []string, args ...string) {
+	t.Helper()
+
+	output, err := execMaker(t, dir, env, args...)
+	if err != nil {
+		t.Fatalf("maker %v: %v\n%s", args, err, output)
+	}
+}
+
+func writeFile(t *testing.T, filename, contents string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(filename), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filename, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertExists(t *testing.T, filenames ...string) {
+	t.Helper()
+
+	for _, filename := range filenames {
+		_, err := os.Stat(filename)
+		if err != nil {
+			t.Errorf("got no %s: %v", filename, err)
+		}
+	}
+}
+
+// snippetsRepository creates an on-disk repository with the go snippet tagged
+// as 1.0.0, and returns its path
+func snippetsRepository(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "snippets", "go.mk"), "go\n")
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = worktree.Add("snippets/go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := worktree.Commit("1.0.0", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.CreateTag("1.0.0", hash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestProjectPaths(t *testing.T) {
+	repository := snippetsRepository(t)
+
+	config := "repositories:\n- url: " + filepath.ToSlash(repository) + "\n  snippets:\n    go: '*'\n"
+
+	tests := []struct {
+		name  string
+		env   []string
+		args  []string
+		files []string
+	}{
+		{
+			name:  "dir flag",
+			args:  []string{"-C", "project", "install"},
+			files: []string{"maker.yaml", "maker.lock", ".make/go.mk"},
+		},
+		{
+			name:  "file flags",
+			args:  []string{"--dir", "project", "--config", "custom.yaml", "--lock", "custom.lock", "--snippets-dir", "snippets", "install"},
+			files: []string{"custom.yaml", "custom.lock", "snippets/go.mk"},
+		},
+		{
+			name:  "environment",
+			env:   []string{"MAKER_DIR=project", "MAKER_CONFIG=custom.yaml", "MAKER_LOCK=custom.lock", "MAKER_SNIPPETS_DIR=snippets"},
+			args:  []string{"install"},
+			files: []string{"custom.yaml", "custom.lock", "snippets/go.mk"},
+		},
+		{
+			name:  "flags over environment",
+			env:   []string{"MAKER_DIR=elsewhere", "MAKER_LOCK=other.lock"},
+			args:  []string{"-C", "project", "--lock", "custom.lock", "install"},
+			files: []string{"maker.yaml", "custom.lock", ".make/go.mk"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			project := filepath.Join(dir, "project")
+
+			writeFile(t, filepath.Join(project, test.files[0]), config)
+
+			runMaker(t, dir, test.env, test.args...)
+
+			for index := range test.files {
+				test.files[index] = filepath.Join(project, test.files[index])
+			}
+
+			assertExists(t, test.files...)
+
+			_, err := os.Stat(filepath.Join(dir, "maker.lock"))
+			if err == nil {
+				t.Error("got a lock file on the working directory")
+			}
+		})
+	}
+}
+
+func TestWorkspaceMember(t *testing.T) {
+	repository := t.TempDir()
+	writeFile(t, filepath.Join(repository, "snippets", "go.mk"), "go\n")
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a", "maker.yaml"), "repositories:\n- url: "+filepath.ToSlash(repository)+"\n  snippets:\n    go: '*'\n")
+	writeFile(t, filepath.Join(root, "b", "maker.yaml"), "repositories: [\n")
+
+	// members without a shared lock are opened on their own
+	writeFile(t, filepath.Join(root, "maker-workspace.yaml"), "members:\n- a\n- b\n")
+
+	runMaker(t, root, nil, "-C", "a", "install")
+	assertExists(t, filepath.Join(root, "a", "maker.lock"), filepath.Join(root, "a", ".make", "go.mk"))
+
+	_, err := os.Stat(filepath.Join(root, "b", ".make"))
+	if err == nil {
+		t.Error("got the broken member opened")
+	}
+
+	// members with a shared lock need the whole workspace
+	writeFile(t, filepath.Join(root, "maker-workspace.yaml"), "members:\n- a\n- b\nsharedLock: true\n")
+
+	output, err := execMaker(t, root, nil, "-C", "a", "install")
+	if err == nil || !strings.Contains(string(output), "member b") {
+		t.Errorf("got error %v with output %q, want the broken member to fail", err, output)
+	}
+}
+
+func TestLogFlags(t *testing.T) {
+	repository := snippetsRepository(t)
+
+	config := "repositories:\n- url: " + filepath.ToSlash(repository) + "\n  snippets:\n    go: '*'\n"
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		notWant string
+		fails   bool
+	}{
+		{name: "default", want: "go", notWant: "DEBUG"},
+		{name: "verbose", args: []string{"--verbose"}, want: `level=DEBUG msg="writing file"`},
+		{name: "json", args: []string{"-v", "--log-format", "json"}, want: `"level":"DEBUG","msg":"writing file"`},
+		{name: "quiet", args: []string{"--quiet"}, notWant: "go"},
+		{name: "verbose and quiet", args: []string{"-v", "-q"}, want: "mutually exclusive", fails: true},
+		{name: "unknown format", args: []string{"--log-format", "xml"}, want: "unknown log format xml", fails: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "maker.yaml"), config)
+
+			output, err := execMaker(t, dir, nil, append(test.args, "install")...)
+			if (err != nil) != test.fails {
+				t.Fatalf("got error %v, want failure %t\n%s", err, test.fails, output)
+			}
+
+			if test.want != "" && !strings.Contains(string(output), test.want) {
+				t.Errorf("got output without %q:\n%s", test.want, output)
+			}
+
+			if test.notWant != "" && strings.Contains(string(output), test.notWant) {
+				t.Errorf("got output with %q:\n%s", test.notWant, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "lists outdated snippets",
+	Long:  "resolves all snippet pins without installing them, and lists the snippets that would change on update or have newer versions out of their pin range",
+	RunE:  outdatedRun,
+	Args:  cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+}
+
+func outdatedRun(cmd *cobra.Command, args []string) (err error) {
+	writer := tabwriter.NewWriter(output, 0, 4, 2, ' ', 0)
+
+	if workspaceMode() {
+		outdated, err := ws.OutdatedContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if len(outdated) > 0 {
+			fmt.Fprintln(writer, "MEMBER\tSNIPPET\tPIN\tCURRENT\tWANTED\tLATEST")
+		}
+
+		for _, item := range outdated {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Member, item.Snippet, item.Pin, item.Current, item.Wanted, item.Latest)
+		}
+
+		err = writer.Flush()
+		if err != nil {
+			return err
+		}
+
+		printDivergences()
+
+		return nil
+	}
+
+	outdated, err := mk.OutdatedContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if len(outdated) > 0 {
+		fmt.Fprintln(writer, "SNIPPET\tPIN\tCURRENT\tWANTED\tLATEST")
+	}
+
+	for _, item := range outdated {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", item.Snippet, item.Pin, item.Current, item.Wanted, item.Latest)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/wwmoraes/maker"
+)
+
+// printEvent prints the outcome of snippet operations, with colors if the
+// output supports them
+func printEvent(event maker.Event) {
+	name := color.MagentaString(event.Snippet)
+	if event.Kind == maker.EventConflict {
+		name = color.RedString(event.Snippet)
+	}
+
+	if event.Project != "" {
+		name = fmt.Sprintf("%s (%s)", name, event.Project)
+	}
+
+	switch event.Kind {
+	case maker.EventSkipped:
+		fmt.Fprintln(output, "skipped  ", name)
+	case maker.EventUpdated:
+		fmt.Fprintln(output, "updated  ", name)
+	case maker.EventKept:
+		fmt.Fprintln(output, "kept     ", name)
+	case maker.EventMerged:
+		fmt.Fprintln(output, "merged   ", name)
+	case maker.EventConflict:
+		fmt.Fprintln(output, "conflict ", name)
+	case maker.EventRemoved:
+		fmt.Fprintln(output, "removed  ", name)
+	case maker.EventPatched:
+		fmt.Fprintln(output, "patched  ", name)
+	case maker.EventUnpatched:
+		fmt.Fprintln(output, "unpatched", name)
+	case maker.EventVendored:
+		fmt.Fprintln(output, "vendored ", name)
+	}
+}
+
+// printDivergences warns about snippets locked to different revisions across
+// workspace members
+func printDivergences() {
+	for _, divergence := range ws.Divergences() {
+		members := make([]string, 0, len(divergence.Revisions))
+		for _, dir := range ws.Members() {
+			revision, exists := divergence.Revisions[dir]
+			if exists {
+				members = append(members, fmt.Sprintf("%s@%s", dir, revision))
+			}
+		}
+
+		fmt.Fprintln(output, "diverged ", color.YellowString(divergence.Snippet), strings.Join(members, ", "))
+	}
+}
+
+// progressBar renders the progress of repository fetches on a single terminal
+// line, which is cleared once the fetch finishes
+type progressBar struct {
+	writer io.Writer
+	width  int
+}
+
+// Notify implements maker.Subscriber
+func (bar *progressBar) Notify(event maker.Event) {
+	switch event.Kind {
+	case maker.EventCloning:
+		fmt.Fprintf(bar.writer, "\r\033[Kcloning %s", event.Repository)
+	case maker.EventCloneProgress:
+		if event.Total == 0 {
+			fmt.Fprintf(bar.writer, "\r\033[Kcloning %s: %s", event.Repository, event.Message)
+			return
+		}
+
+		filled := bar.width * event.Current / event.Total
+		fmt.Fprintf(bar.writer, "\r\033[Kcloning %s: %s [%s%s] %3d%%",
+			event.Repository,
+			event.Message,
+			strings.Repeat("=", filled),
+			strings.Repeat(" ", bar.width-filled),
+			100*event.Current/event.Total,
+		)
+	case maker.EventCloned:
+		fmt.Fprint(bar.writer, "\r\033[K")
+	}
+}
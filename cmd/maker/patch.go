@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var patchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "stores local snippet changes as a patch",
+	Long:  "generates a patch from the local changes of a snippet, which is applied on top of the upstream contents whenever it is installed",
+	RunE:  patchRun,
+	Args:  cobra.ExactArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(patchCmd)
+}
+
+func patchRun(cmd *cobra.Command, args []string) (err error) {
+	err = requireProject()
+	if err != nil {
+		return err
+	}
+
+	err = mk.PatchContext(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -17,7 +17,12 @@ func init() {
 }
 
 func removeRun(cmd *cobra.Command, args []string) (err error) {
-	err = mk.Remove(args[0])
+	err = requireProject()
+	if err != nil {
+		return err
+	}
+
+	err = mk.RemoveContext(cmd.Context(), args[0])
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wwmoraes/maker"
+)
+
+var (
+	updateCmd = &cobra.Command{
+		Use:   "update [snippet...]",
+		Short: "updates snippets",
+		Long:  "resolves the snippet versions and branches again, and installs any changes. Commit pins are never moved",
+		RunE:  updateRun,
+	}
+	updateStrategy string
+)
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().StringVar(&updateStrategy, "strategy", string(maker.StrategyMerge), "how to handle local snippet changes: ours, theirs, merge or fail")
+}
+
+func updateRun(cmd *cobra.Command, args []string) (err error) {
+	strategy, err := maker.ParseMergeStrategy(updateStrategy)
+	if err != nil {
+		return err
+	}
+
+	if workspaceMode() {
+		err = ws.UpdateContext(cmd.Context(), strategy, args...)
+
+		printDivergences()
+
+		return err
+	}
+
+	err = mk.UpdateContext(cmd.Context(), strategy, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
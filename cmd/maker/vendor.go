@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var vendorCmd = &cobra.Command{
+	Use:   "vendor",
+	Short: "vendors locked snippets",
+	Long:  "exports the locked contents of snippets from remote repositories to the vendor directory, so they can be installed with --offline",
+	RunE:  vendorRun,
+	Args:  cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(vendorCmd)
+}
+
+func vendorRun(cmd *cobra.Command, args []string) error {
+	if workspaceMode() {
+		return ws.VendorContext(cmd.Context())
+	}
+
+	return mk.VendorContext(cmd.Context())
+}
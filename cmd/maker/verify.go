@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "verifies installed snippets",
+	Long:  "checks the installed snippet files against their locked contents without network access, and fails on any difference",
+	RunE:  verifyRun,
+	Args:  cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func verifyRun(cmd *cobra.Command, args []string) (err error) {
+	err = requireProject()
+	if err != nil {
+		return err
+	}
+
+	verification, err := mk.Verify()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range verification.Missing {
+		fmt.Fprintln(output, "missing  ", color.RedString(name))
+	}
+
+	for _, name := range verification.Modified {
+		fmt.Fprintln(output, "modified ", color.YellowString(name))
+	}
+
+	for _, name := range verification.Untracked {
+		fmt.Fprintln(output, "untracked", color.YellowString(name))
+	}
+
+	if !verification.OK() {
+		return fmt.Errorf("installed snippets do not match the lock file")
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var rootCmd = &cobra.Command{
+	Use:          "makerctl",
+	Short:        "make-all-the-things snippet authoring tool",
+	Long:         "manages and publishes the snippets that maker installs on projects",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	err := rootCmd.ExecuteContext(ctx)
+
+	stop()
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wwmoraes/maker"
+)
+
+var snippetCmd = &cobra.Command{
+	Use:   "snippet",
+	Short: "manages snippets",
+}
+
+var snippetPushCmd = &cobra.Command{
+	Use:   "push file registry/namespace/name:version",
+	Short: "publishes a snippet to an OCI registry",
+	Long:  "publishes a snippet file version as an OCI artifact, which maker installs from repositories with an oci://registry/namespace URL. Plain HTTP registries are referenced with oci+http://",
+	RunE:  snippetPushRun,
+	Args:  cobra.ExactArgs(2),
+}
+
+var (
+	pushDescription string
+	pushUser        string
+	pushPasswordEnv string
+	pushTokenEnv    string
+)
+
+func init() {
+	snippetPushCmd.Flags().StringVar(&pushDescription, "description", "", "snippet description, stored on the artifact metadata")
+	snippetPushCmd.Flags().StringVar(&pushUser, "user", "", "registry username")
+	snippetPushCmd.Flags().StringVar(&pushPasswordEnv, "password-env", "", "environment variable with the registry password")
+	snippetPushCmd.Flags().StringVar(&pushTokenEnv, "token-env", "", "environment variable with the registry access token")
+
+	snippetCmd.AddCommand(snippetPushCmd)
+	rootCmd.AddCommand(snippetCmd)
+}
+
+func snippetPushRun(cmd *cobra.Command, args []string) error {
+	contents, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	// credentials are looked up as configured on maker.yaml repositories
+	auth := &maker.Auth{User: pushUser}
+
+	switch {
+	case pushPasswordEnv != "" && pushTokenEnv != "":
+		return fmt.Errorf("--password-env and --token-env are mutually exclusive")
+	case pushPasswordEnv != "":
+		auth.Method = maker.AuthBasic
+		auth.PasswordEnv = pushPasswordEnv
+	case pushTokenEnv != "":
+		auth.Method = maker.AuthToken
+		auth.TokenEnv = pushTokenEnv
+	}
+
+	digest, err := maker.PushSnippetContext(cmd.Context(), maker.PushOptions{
+		Reference:   args[1],
+		Contents:    contents,
+		Description: pushDescription,
+		Auth:        auth,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "pushed    %s@%s\n", args[1], digest)
+
+	return nil
+}
@@ -5,6 +5,8 @@ import "fmt"
 // Config stores the snippets required and any extra Maker setting
 type Config struct {
 	Repositories []*Repository `yaml:"repositories"`
+	// Mirrors rewrite the repository URLs before they are fetched
+	Mirrors []Mirror `yaml:"mirrors,omitempty"`
 }
 
 func (config *Config) AddRepository(repo *Repository) error {
@@ -35,15 +37,33 @@ func (config *Config) GetRepository(reference string) (*Repository, error) {
 		return config.Repositories[0], nil
 	}
 
-	for _, repository := range config.Repositories {
-		if repository.Alias == reference {
-			return repository, nil
+	repository := findRepository(config.Repositories, reference)
+	if repository == nil {
+		return nil, fmt.Errorf("repository not found")
+	}
+
+	return repository, nil
+}
+
+// findRepository returns the repository with the alias or URL, or nil if there
+// is none
+func findRepository(repositories []*Repository, reference string) *Repository {
+	for _, repository := range repositories {
+		if repository.Alias == reference || repository.URL == reference {
+			return repository
 		}
+	}
 
-		if repository.URL == reference {
+	return nil
+}
+
+// GetSnippetRepository returns the repository that provides the snippet
+func (config *Config) GetSnippetRepository(name string) (*Repository, error) {
+	for _, repository := range config.Repositories {
+		if repository.HasSnippet(name) {
 			return repository, nil
 		}
 	}
 
-	return nil, fmt.Errorf("repository not found")
+	return nil, fmt.Errorf("snippet %s not found", name)
 }
@@ -0,0 +1,157 @@
+package maker
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EventKind identifies a step of a Maker operation
+type EventKind string
+
+const (
+	// EventCloning is emitted before a repository is fetched
+	EventCloning EventKind = "cloning"
+	// EventCloneProgress reports the remote progress while fetching a
+	// repository, if the remote supports it
+	EventCloneProgress EventKind = "clone-progress"
+	// EventCloned is emitted after a repository is fetched
+	EventCloned EventKind = "cloned"
+	// EventResolving is emitted before a snippet pin is resolved to a commit
+	EventResolving EventKind = "resolving"
+	// EventResolved is emitted after a snippet pin is resolved to a commit
+	EventResolved EventKind = "resolved"
+	// EventInstalling is emitted before a snippet file is installed
+	EventInstalling EventKind = "installing"
+	// EventSkipped means the snippet file was already up to date
+	EventSkipped EventKind = "skipped"
+	// EventUpdated means the snippet file was written with its locked contents
+	EventUpdated EventKind = "updated"
+	// EventKept means the snippet file local modifications were kept as is
+	EventKept EventKind = "kept"
+	// EventMerged means the snippet file upstream changes were merged with its
+	// local modifications
+	EventMerged EventKind = "merged"
+	// EventConflict means the snippet file was written with merge conflicts
+	EventConflict EventKind = "conflict"
+	// EventRemoved means the snippet was removed from the configuration
+	EventRemoved EventKind = "removed"
+	// EventPatched means the snippet local modifications were stored as a patch
+	EventPatched EventKind = "patched"
+	// EventUnpatched means the snippet patch was removed
+	EventUnpatched EventKind = "unpatched"
+	// EventVendored means the snippet locked contents were exported to the
+	// vendor directory
+	EventVendored EventKind = "vendored"
+)
+
+// Event describes a step of a Maker operation. Fields that do not apply to
+// the event kind are left empty
+type Event struct {
+	Kind EventKind
+	// Project identifies the instance that emitted the event, such as its
+	// workspace member directory
+	Project    string
+	Repository string
+	Snippet    string
+	Pin        string
+	Version    string
+	Commit     string
+	// Message is the remote progress stage, such as "Receiving objects"
+	Message string
+	// Current and Total are the remote progress stage counters, if known
+	Current, Total int
+}
+
+// Subscriber receives the events of Maker operations. Events are delivered
+// synchronously and one at a time, in the order they happen. Repositories
+// fetched by concurrent jobs have their events interleaved, while snippet
+// installation events are always in the configuration order
+type Subscriber interface {
+	Notify(event Event)
+}
+
+// SubscriberFunc adapts a function into a Subscriber
+type SubscriberFunc func(event Event)
+
+// Notify implements Subscriber
+func (fn SubscriberFunc) Notify(event Event) {
+	fn(event)
+}
+
+// WithSubscriber adds a subscriber to the events of all operations
+func WithSubscriber(subscriber Subscriber) Option {
+	return func(o *options) {
+		o.subscribers = append(o.subscribers, subscriber)
+	}
+}
+
+// emit delivers the event to all subscribers
+func (mk *Maker) emit(event Event) {
+	event.Project = mk.project
+
+	mk.emitMutex.Lock()
+	defer mk.emitMutex.Unlock()
+
+	for _, subscriber := range mk.subscribers {
+		subscriber.Notify(event)
+	}
+}
+
+var progressCountersRule = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+
+// progressWriter converts the remote sideband progress output into events
+type progressWriter struct {
+	mk         *Maker
+	repository string
+	buffer     bytes.Buffer
+}
+
+// Write implements io.Writer. Progress lines are terminated by either carriage
+// returns or line feeds
+func (writer *progressWriter) Write(data []byte) (int, error) {
+	writer.buffer.Write(data)
+
+	for {
+		contents := writer.buffer.Bytes()
+		index := bytes.IndexAny(contents, "\r\n")
+		if index == -1 {
+			break
+		}
+
+		line := string(contents[:index])
+		writer.buffer.Next(index + 1)
+
+		if strings.TrimSpace(line) != "" {
+			writer.mk.emit(progressEvent(writer.repository, line))
+		}
+	}
+
+	return len(data), nil
+}
+
+// progressEvent parses a remote progress line such as
+// "Receiving objects:  45% (9/20), 1.20 KiB | 1.20 MiB/s"
+func progressEvent(repository, line string) Event {
+	event := Event{
+		Kind:       EventCloneProgress,
+		Repository: repository,
+		Message:    strings.TrimSpace(line),
+	}
+
+	stage, _, found := strings.Cut(line, ":")
+	if !found {
+		return event
+	}
+
+	event.Message = strings.TrimSpace(stage)
+
+	matches := progressCountersRule.FindStringSubmatch(line)
+	if matches != nil {
+		event.Current, _ = strconv.Atoi(matches[1])
+		event.Total, _ = strconv.Atoi(matches[2])
+	}
+
+	return event
+}
@@ -0,0 +1,96 @@
+package maker_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+// eventRecorder keeps the kind and snippet of every event, except for the
+// clone progress ones
+type eventRecorder []string
+
+func (recorder *eventRecorder) Notify(event maker.Event) {
+	if event.Kind == maker.EventCloneProgress {
+		return
+	}
+
+	*recorder = append(*recorder, fmt.Sprintf("%s %s", event.Kind, event.Snippet))
+}
+
+func TestEvents(t *testing.T) {
+	var first, second eventRecorder
+
+	url := localSnippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+		"1.1.0": "one dot one\n",
+	})
+
+	project := memfs.New()
+
+	err := util.WriteFile(project, maker.ConfFilename, pinConfig(url, "^1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := make([]maker.Event, 0)
+
+	mk := newProjectMaker(t, project,
+		maker.WithSubscriber(&first),
+		maker.WithSubscriber(&second),
+		maker.WithSubscriber(maker.SubscriberFunc(func(event maker.Event) {
+			if event.Kind == maker.EventResolved {
+				resolved = append(resolved, event)
+			}
+		})),
+	)
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the repository is fetched once, and the snippet is up to date already
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Remove("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"cloning ",
+		"cloned ",
+		"resolving go",
+		"resolved go",
+		"installing go",
+		"updated go",
+		"installing go",
+		"skipped go",
+		"removed go",
+	}
+
+	if strings.Join(first, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got events %v, want %v", first, want)
+	}
+
+	if strings.Join(second, ", ") != strings.Join(first, ", ") {
+		t.Errorf("got events %v on the second subscriber, want %v", second, first)
+	}
+
+	if len(resolved) != 1 {
+		t.Fatalf("got %d resolved events, want 1", len(resolved))
+	}
+
+	event := resolved[0]
+	if event.Repository != url || event.Pin != "^1" || event.Version != "1.1.0" || event.Commit == "" {
+		t.Errorf("got resolved event %+v, want the repository, pin, version and commit", event)
+	}
+}
@@ -0,0 +1,108 @@
+package maker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+func TestInstallFrozen(t *testing.T) {
+	url := localSnippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+	})
+
+	config := string(pinConfig(url, "^1"))
+
+	project := memfs.New()
+
+	err := util.WriteFile(project, maker.ConfFilename, []byte(config), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newProjectMaker(t, project).Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := util.ReadFile(project, maker.LockFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lockData := string(data)
+	hash := lockData[strings.Index(lockData, "hash: ")+len("hash: "):]
+	hash = hash[:strings.Index(hash, "\n")]
+
+	tests := []struct {
+		name   string
+		config string
+		lock   string
+		want   string
+	}{
+		{name: "locked", config: config, lock: lockData},
+		{name: "missing", config: config, lock: "", want: "missing an entry for snippet go"},
+		{name: "extra", config: "repositories:\n- alias: test\n  url: " + url + "\n  snippets: {}\n", lock: lockData, want: "not present on maker.yaml"},
+		{name: "pin", config: string(pinConfig(url, "=1.0.0")), lock: lockData, want: "is locked to ^1 instead of =1.0.0"},
+		{name: "stale", config: config, lock: strings.Replace(lockData, hash, strings.Repeat("0", len(hash)), 1), want: "entry for snippet go is out of date"},
+	}
+
+	for _, test := range tests {
+		project := memfs.New()
+
+		err = util.WriteFile(project, maker.ConfFilename, []byte(test.config), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = util.WriteFile(project, maker.LockFilename, []byte(test.lock), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = newProjectMaker(t, project).InstallFrozen(maker.StrategyTheirs)
+
+		if test.want == "" {
+			if err != nil {
+				t.Errorf("%s: got error %v", test.name, err)
+			}
+
+			_, err = project.Stat(maker.SnippetsDirectory + "/go.mk")
+			if err != nil {
+				t.Errorf("%s: got no snippet installed: %v", test.name, err)
+			}
+
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.want)
+		}
+
+		for filename, want := range map[string]string{
+			maker.ConfFilename: test.config,
+			maker.LockFilename: test.lock,
+		} {
+			data, err := util.ReadFile(project, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != want {
+				t.Errorf("%s: got %s written", test.name, filename)
+			}
+		}
+
+		infos, err := project.ReadDir(maker.SnippetsDirectory)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(infos) != 0 {
+			t.Errorf("%s: got %d files written to the snippets directory", test.name, len(infos))
+		}
+	}
+}
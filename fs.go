@@ -26,6 +26,21 @@ type Unlockable interface {
 	Unlock() error
 }
 
+// Syncable represents an IO object that supports flushing its contents to the
+// underlying storage media
+type Syncable interface {
+	// Sync commits the current contents to stable storage e.g. fsync
+	Sync() error
+}
+
+// Replaceable represents an IO object that supports replacing all of its
+// contents atomically
+type Replaceable interface {
+	// Replace swaps the contents with the data, such that readers see either
+	// the previous or the new contents, but never a partial write
+	Replace(data []byte) error
+}
+
 // File represents a descriptor object that supports read, write, seek, truncate
 // and lock operations.
 type File interface {
@@ -0,0 +1,114 @@
+package maker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// WithJobs sets how many repositories are fetched, and snippets resolved, at
+// the same time. It defaults to one
+func WithJobs(jobs int) Option {
+	return func(o *options) {
+		o.jobs = jobs
+	}
+}
+
+// forEach calls the function with every index up to count, running up to the
+// configured jobs at the same time. All errors are returned joined, in index
+// order, or only the context one if it is done, as no more calls are started
+func (mk *Maker) forEach(ctx context.Context, count int, fn func(index int) error) error {
+	errs := make([]error, count)
+	semaphore := make(chan struct{}, mk.jobs)
+
+	var wg sync.WaitGroup
+
+	for index := 0; index < count; index++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			errs[index] = fn(index)
+		}(index)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
+
+// initRepositories fetches all configured repositories
+func (mk *Maker) initRepositories(ctx context.Context) error {
+	return mk.forEach(ctx, len(mk.conf.Repositories), func(index int) error {
+		return mk.initRepository(ctx, mk.conf.Repositories[index])
+	})
+}
+
+// snippetTask is a configured snippet to be processed
+type snippetTask struct {
+	repository *Repository
+	name       string
+	pin        Pin
+}
+
+// snippetTasks returns the configured snippets that pass the filter, or all of
+// them if it is empty, in a stable order so results are reported the same way
+// on every run
+func (mk *Maker) snippetTasks(filter map[string]bool) []snippetTask {
+	tasks := make([]snippetTask, 0)
+
+	for _, repository := range mk.conf.Repositories {
+		names := make([]string, 0, len(repository.Snippets))
+		for name := range repository.Snippets {
+			if len(filter) == 0 || filter[name] {
+				names = append(names, name)
+			}
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			tasks = append(tasks, snippetTask{repository, name, repository.Snippets[name]})
+		}
+	}
+
+	return tasks
+}
+
+// upstreamContents returns the snippet contents at the source ID. IDs are
+// immutable, so contents are fetched only once
+func (mk *Maker) upstreamContents(repository *Repository, name, id string) ([]byte, error) {
+	key := fmt.Sprintf("%s\x00%s\x00%s", repository.URL, id, name)
+
+	mk.upstreamMutex.Lock()
+	data, cached := mk.upstream[key]
+	mk.upstreamMutex.Unlock()
+
+	if cached {
+		return data, nil
+	}
+
+	data, err := repository.Get(id, name)
+	if err != nil {
+		return nil, err
+	}
+
+	mk.upstreamMutex.Lock()
+	mk.upstream[key] = data
+	mk.upstreamMutex.Unlock()
+
+	return data, nil
+}
@@ -0,0 +1,66 @@
+package maker_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/wwmoraes/maker"
+)
+
+func TestInstallJobs(t *testing.T) {
+	config := "repositories:\n"
+	names := []string{"a", "b", "c", "d", "e", "f"}
+
+	// spreads the snippets over a few repositories
+	for index := 0; index < len(names); index += 2 {
+		dir := t.TempDir()
+		config += "- url: " + dir + "\n  snippets:\n"
+
+		for _, name := range names[index : index+2] {
+			writeTestFile(t, filepath.Join(dir, "snippets", name+".mk"), name+"\n")
+			config += "    " + name + ": '*'\n"
+		}
+	}
+
+	updated := make([]string, 0)
+	subscriber := maker.SubscriberFunc(func(event maker.Event) {
+		if event.Kind == maker.EventUpdated {
+			updated = append(updated, event.Snippet)
+		}
+	})
+
+	mk, err := maker.New(maker.NewMemoryFile([]byte(config)), maker.NewMemoryFile(nil), memfs.New(), maker.WithJobs(4), maker.WithSubscriber(subscriber))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(updated, " ") != strings.Join(names, " ") {
+		t.Errorf("got snippets updated in order %v, want %v", updated, names)
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "snippets", "go.mk"), "go\n")
+
+	lock := maker.NewMemoryFile(nil)
+
+	mk, err = maker.New(maker.NewMemoryFile([]byte("repositories:\n- url: "+dir+"\n  snippets:\n    go: '*'\n    node: '*'\n    rust: '*'\n")), lock, memfs.New(), maker.WithJobs(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err == nil || !strings.Contains(err.Error(), "snippet node") || !strings.Contains(err.Error(), "snippet rust") {
+		t.Errorf("got error %v, want errors for both node and rust", err)
+	}
+
+	if len(lock.Bytes()) != 0 {
+		t.Errorf("got lock written despite errors:\n%s", lock.Bytes())
+	}
+}
@@ -0,0 +1,151 @@
+package maker_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/wwmoraes/maker"
+)
+
+func localConfig(url string) string {
+	return "repositories:\n- alias: local\n  url: " + url + "\n  snippets: {}\n"
+}
+
+func TestLocalGitRepository(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		writeTestFile(t, filepath.Join(dir, "snippets", "go.mk"), version+"\n")
+
+		_, err = worktree.Add("snippets/go.mk")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := worktree.Commit(version, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = repo.CreateTag(version, hash, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// uncommitted changes are not part of any version
+	writeTestFile(t, filepath.Join(dir, "snippets", "go.mk"), "work in progress\n")
+
+	directory := memfs.New()
+	lock := maker.NewMemoryFile(nil)
+
+	mk, err := maker.New(maker.NewMemoryFile([]byte(localConfig("file://"+filepath.ToSlash(dir)))), lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("local:go@~1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(lock.Bytes(), []byte("version: 1.0.0")) {
+		t.Errorf("lock does not contain the version:\n%s", lock.Bytes())
+	}
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "1.0.0\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "1.0.0\n")
+	}
+}
+
+func TestLocalDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "snippets", "go.mk"), "one\n")
+
+	conf := maker.NewMemoryFile([]byte(localConfig(dir)))
+	lock := maker.NewMemoryFile(nil)
+	directory := memfs.New()
+
+	mk, err := maker.New(conf, lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("local:go@^1")
+	if err == nil {
+		t.Error("got no error pinning a plain directory snippet to a version")
+	}
+
+	err = mk.Add("local:go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "one\n")
+	}
+
+	writeTestFile(t, filepath.Join(dir, "snippets", "go.mk"), "two\n")
+
+	// directories are read once per instance
+	mk, err = maker.New(conf, lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outdated, err := mk.Outdated()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outdated) != 1 || outdated[0].Snippet != "go" {
+		t.Errorf("got outdated snippets %+v, want go only", outdated)
+	}
+
+	err = mk.InstallFrozen(maker.StrategyFail)
+	if err == nil {
+		t.Error("got no error installing a stale lock frozen")
+	}
+
+	err = mk.Install(false, maker.StrategyFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "two\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "two\n")
+	}
+}
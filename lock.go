@@ -1,30 +1,127 @@
 package maker
 
-// Lock stores the md5 hash sum for each managed snippet
-type Lock map[string]map[string]string
+import (
+	"fmt"
+)
 
-func (lock Lock) Set(repo, name, version string) {
-	if lock[repo] == nil {
-		lock[repo] = make(map[string]string)
+// LockfileVersion is the lock schema version written by this release. Lock
+// files with a higher version are rejected, while lower ones are migrated
+const LockfileVersion = 2
+
+// LockEntry stores the resolved state of a snippet
+type LockEntry struct {
+	// Constraint is the pin requested on the configuration
+	Constraint string `yaml:"constraint"`
+	// Version is the resolved version, if the pin is a version constraint
+	Version string `yaml:"version,omitempty"`
+	// Tag is the tag or branch name the commit was resolved from, if any
+	Tag string `yaml:"tag,omitempty"`
+	// Commit is the resolved commit hash, or the immutable revision ID of
+	// sources other than git
+	Commit string `yaml:"commit"`
+	// Hash is the git blob hash of the upstream snippet contents
+	Hash string `yaml:"hash,omitempty"`
+	// Patched is the git blob hash of the installed snippet contents, if a
+	// local patch is applied on top of the upstream ones
+	Patched string `yaml:"patched,omitempty"`
+	// Path is the installed snippet file path, relative to the project root
+	Path string `yaml:"path,omitempty"`
+}
+
+// Lock stores the resolved state of each managed snippet, per repository URL
+type Lock struct {
+	Version      int                              `yaml:"lockfileVersion"`
+	Repositories map[string]map[string]*LockEntry `yaml:"repositories"`
+}
+
+// NewLock returns an empty lock on the current schema version
+func NewLock() *Lock {
+	return &Lock{
+		Version:      LockfileVersion,
+		Repositories: make(map[string]map[string]*LockEntry),
 	}
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, migrating older lock schemas to
+// the current version
+func (lock *Lock) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var header struct {
+		Version int `yaml:"lockfileVersion"`
+	}
+
+	err := unmarshal(&header)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case header.Version > LockfileVersion:
+		return fmt.Errorf("unsupported lockfile version %d, the latest supported is %d", header.Version, LockfileVersion)
+	case header.Version == LockfileVersion:
+		type plainLock Lock
+
+		data := plainLock(*NewLock())
+
+		err = unmarshal(&data)
+		if err != nil {
+			return err
+		}
 
-	lock[repo][name] = version
+		*lock = Lock(data)
+	default:
+		// version 1 has no header, and maps repository URLs and snippet names
+		// directly to the commit hash
+		var data map[string]map[string]string
+
+		err = unmarshal(&data)
+		if err != nil {
+			return err
+		}
+
+		*lock = *NewLock()
+		for repo, snippets := range data {
+			for name, commit := range snippets {
+				lock.Set(repo, name, &LockEntry{Commit: commit})
+			}
+		}
+	}
+
+	if lock.Repositories == nil {
+		lock.Repositories = make(map[string]map[string]*LockEntry)
+	}
+
+	return nil
 }
 
-func (lock Lock) Get(repo, name string) string {
-	repoLock, exists := lock[repo]
+// Set stores the snippet entry for the repository
+func (lock *Lock) Set(repo, name string, entry *LockEntry) {
+	if lock.Repositories[repo] == nil {
+		lock.Repositories[repo] = make(map[string]*LockEntry)
+	}
+
+	lock.Repositories[repo][name] = entry
+}
+
+// Get returns the snippet entry for the repository, or nil if there's none
+func (lock *Lock) Get(repo, name string) *LockEntry {
+	repoLock, exists := lock.Repositories[repo]
 	if !exists {
-		return ""
+		return nil
 	}
 
 	return repoLock[name]
 }
 
-func (lock Lock) Unset(repo, name string) {
-	repoLock, exists := lock[repo]
+// Unset removes the snippet entry for the repository
+func (lock *Lock) Unset(repo, name string) {
+	repoLock, exists := lock.Repositories[repo]
 	if !exists {
 		return
 	}
 
 	delete(repoLock, name)
+
+	if len(repoLock) == 0 {
+		delete(lock.Repositories, repo)
+	}
 }
@@ -0,0 +1,152 @@
+package maker_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+func TestLockPinChanged(t *testing.T) {
+	factory := maker.WithRepositoryFactory(snippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+		"1.1.0": "one dot one\n",
+	}))
+
+	directory := memfs.New()
+	lock := maker.NewMemoryFile(nil)
+
+	mk, err := maker.New(maker.NewMemoryFile(pinConfig("https://example.com/snippets.git", "^1")), lock, directory, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lockData := lock.Bytes()
+
+	// no version satisfies the new pin
+	lock = maker.NewMemoryFile(lockData)
+
+	mk, err = maker.New(maker.NewMemoryFile(pinConfig("https://example.com/snippets.git", "^2")), lock, directory, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err == nil || !strings.Contains(err.Error(), "no matching version") {
+		t.Errorf("got error %v, want no matching version", err)
+	}
+
+	if string(lock.Bytes()) != string(lockData) {
+		t.Errorf("got lock changed:\n%s", lock.Bytes())
+	}
+
+	lock = maker.NewMemoryFile(lockData)
+
+	mk, err = maker.New(maker.NewMemoryFile(pinConfig("https://example.com/snippets.git", "=1.0.0")), lock, directory, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"constraint: =1.0.0", "version: 1.0.0"} {
+		if !strings.Contains(string(lock.Bytes()), want) {
+			t.Errorf("lock does not contain %q:\n%s", want, lock.Bytes())
+		}
+	}
+}
+
+func TestLockCommitPinChanged(t *testing.T) {
+	dir := t.TempDir()
+	commit := newUpstreamRepository(t, dir)
+	url := filepath.ToSlash(dir)
+
+	first := commit("one\n", "", false)
+	second := commit("two\n", "", false)
+
+	directory := memfs.New()
+	lock := maker.NewMemoryFile(nil)
+
+	mk, err := maker.New(maker.NewMemoryFile(pinConfig(url, "commit:"+first.String())), lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock = maker.NewMemoryFile(lock.Bytes())
+
+	mk, err = maker.New(maker.NewMemoryFile(pinConfig(url, "commit:"+second.String())), lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Update(maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(lock.Bytes()), "commit: "+second.String()) {
+		t.Errorf("got lock not moved to the new commit pin:\n%s", lock.Bytes())
+	}
+}
+
+func TestLockMigration(t *testing.T) {
+	factory := snippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+		"1.1.0": "one dot one\n",
+	})
+
+	source, err := factory(maker.FetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := source.Resolve("go", maker.PinTag, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first lock schema has only the commits, without any header
+	lock := maker.NewMemoryFile([]byte("https://example.com/snippets.git:\n  go: " + commit + "\n"))
+	directory := memfs.New()
+
+	mk, err := maker.New(maker.NewMemoryFile(pinConfig("https://example.com/snippets.git", "^1")), lock, directory, maker.WithRepositoryFactory(factory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"lockfileVersion: 2", "constraint: ^1", "version: 1.0.0", "tag: 1.0.0", "commit: " + commit, "hash: "} {
+		if !strings.Contains(string(lock.Bytes()), want) {
+			t.Errorf("lock does not contain %q:\n%s", want, lock.Bytes())
+		}
+	}
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one\n" {
+		t.Errorf("got snippet contents %q, want the locked %q", data, "one\n")
+	}
+}
@@ -1,20 +1,23 @@
 package maker
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"log/slog"
+	"net/http"
 	"os"
-	"runtime"
+	"path"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/fatih/color"
 	"github.com/go-git/go-billy/v5"
-	"github.com/go-git/go-billy/v5/osfs"
-	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/wwmoraes/maker/pkg/diff"
 	"github.com/wwmoraes/maker/pkg/semver"
 )
 
@@ -22,6 +25,9 @@ const (
 	SnippetsDirectory = ".make"
 	ConfFilename      = "maker.yaml"
 	LockFilename      = "maker.lock"
+	// PatchesDirectory is where snippet patches are stored, within the snippets
+	// directory
+	PatchesDirectory = "patches"
 )
 
 // Maker manages the configuration, lock data and snippet files on a directory
@@ -30,145 +36,365 @@ type Maker struct {
 	lockFile   File
 	directory  billy.Filesystem
 	conf       Config
-	lock       Lock
+	lock       *Lock
+	// pending holds the snippet directory file changes that are written on the
+	// next commit, with nil data for removals
+	pending map[string][]byte
+	// closers are released in reverse order when the instance is closed
+	closers     []io.Closer
+	logger      *slog.Logger
+	subscribers []Subscriber
+	// snippetsDirectory, configFilename and lockFilename are the names used
+	// on messages and lock entries, relative to the project root
+	snippetsDirectory string
+	configFilename    string
+	lockFilename      string
+	repositoryFactory RepositoryFactory
+	// project identifies the instance on events, such as its workspace member
+	// directory
+	project string
+	// shared is the workspace lock, if shared among its members
+	shared     *sharedLock
+	httpClient *http.Client
+	// root is the project directory on the OS filesystem, if any, which
+	// relative local repository paths are based on
+	root string
+	// offline resolves remote repositories from the vendor directory only
+	offline bool
+	// user holds the settings that apply to all projects
+	user *UserConfig
+	// cacheDirectory keeps git repositories across runs, if set
+	cacheDirectory string
+	// jobs is how many repositories are fetched at the same time
+	jobs int
+	// upstream caches the snippet contents by repository, ID and name
+	upstream      map[string][]byte
+	upstreamMutex sync.Mutex
+	// emitMutex delivers the events of concurrent jobs one at a time
+	emitMutex sync.Mutex
+	// commits counts the successful commits, so failed operations know if
+	// their changes were written
+	commits int
 }
 
-func closeDescriptor(fd UnlockCloser) error {
-	err := fd.Unlock()
-	if err != nil {
-		return err
-	}
-
-	return fd.Close()
+// NewDefault creates a standard Maker instance using the OS filesystem and the
+// current directory as the repository root. It waits for other maker processes
+// on the same directory to release the project lock, which is held until the
+// instance is closed.
+func NewDefault(opts ...Option) (mk *Maker, err error) {
+	return NewWithOptions(opts...)
 }
 
-// NewDefault creates a standard Maker instance using the OS filesystem and the
-// current directory as the repository root.
-func NewDefault() (mk *Maker, err error) {
-	pwd, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
+// NewWithOptions creates a Maker instance on a root directory, which defaults
+// to the current one on the OS filesystem. The snippets directory is created if
+// needed, and the configuration and lock files are opened from the root, being
+// replaced atomically on every change. Instances on the OS filesystem wait for
+// other maker processes on the same directory to release the project lock,
+// which is held until the instance is closed
+func NewWithOptions(opts ...Option) (mk *Maker, err error) {
+	o := newOptions(opts)
+
+	root := o.filesystem
+	if root == nil {
+		dir := o.root
+		if dir == "" {
+			dir, err = os.Getwd()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
 
-	root := osfs.New(pwd)
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", dir)
+		}
+
+		root = newOSFilesystem(dir)
+		o.root = dir
+	} else if o.root != "" {
+		root, err = root.Chroot(o.root)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// always try to make the directory
-	err = root.MkdirAll(SnippetsDirectory, 0750)
+	err = root.MkdirAll(o.snippetsDirectory, 0750)
 	if err != nil && !os.IsExist(err) {
 		return mk, err
 	}
 
 	var info fs.FileInfo
-	info, err = root.Stat(SnippetsDirectory)
+	info, err = root.Stat(o.snippetsDirectory)
 	if err != nil {
 		return nil, err
 	}
 
 	if !info.Mode().IsDir() {
-		return nil, fmt.Errorf("%s is not a valid directory", SnippetsDirectory)
+		return nil, fmt.Errorf("%s is not a valid directory", o.snippetsDirectory)
 	}
 
 	// make sure we can RWX on the target directory
 	if info.Mode()&0700 == 0 {
-		return nil, fmt.Errorf("%s directory must be readable, writable and executable", SnippetsDirectory)
+		return nil, fmt.Errorf("%s directory must be readable, writable and executable", o.snippetsDirectory)
 	}
 
-	snippetsFS, err := root.Chroot(SnippetsDirectory)
+	snippetsFS, err := root.Chroot(o.snippetsDirectory)
 	if err != nil {
 		return nil, err
 	}
 
-	// check if the maker config file is valid
-	info, err = root.Stat(ConfFilename)
-	if err != nil && !os.IsNotExist(err) {
-		return mk, err
-	}
+	closers := make([]io.Closer, 0, 3)
+	defer func() {
+		if err != nil {
+			closeAll(closers)
+		}
+	}()
 
-	if err == nil && !info.Mode().IsRegular() {
-		return nil, fmt.Errorf("%s is not a valid Maker file", ConfFilename)
+	// other processes can only interfere with files on the OS filesystem
+	if osRoot, ok := root.(*osFilesystem); ok {
+		var (
+			lockFilename string
+			processLock  *ProcessLock
+		)
+
+		lockFilename, err = processLockFilename(osRoot.Join(osRoot.Root(), o.snippetsDirectory))
+		if err != nil {
+			return nil, err
+		}
+
+		processLock, err = AcquireProcessLock(lockFilename, o.lockTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		closers = append(closers, processLock)
 	}
 
-	// check if the maker lock file is valid
-	info, err = root.Stat(LockFilename)
+	// check if the maker config file is valid
+	info, err = root.Stat(o.configFilename)
 	if err != nil && !os.IsNotExist(err) {
 		return mk, err
 	}
 
 	if err == nil && !info.Mode().IsRegular() {
-		return nil, fmt.Errorf("%s is not a valid Maker lock file", LockFilename)
+		return nil, fmt.Errorf("%s is not a valid Maker file", o.configFilename)
 	}
 
 	// open the maker file for usage
-	confFD, err := root.OpenFile(ConfFilename, os.O_RDWR|os.O_CREATE, 0644)
+	confFD, err := openAtomicFile(root, o.configFilename)
 	if err != nil {
-		return mk, err
+		return nil, err
 	}
-	confFD.Lock()
-	runtime.SetFinalizer(confFD, closeDescriptor)
 
-	// open the locker file for usage
-	lockFD, err := root.OpenFile(LockFilename, os.O_RDWR|os.O_CREATE, 0644)
+	closers = append(closers, confFD)
+
+	lockFD, err := openLockFile(root, o)
 	if err != nil {
-		return mk, err
+		return nil, err
+	}
+
+	if closer, ok := lockFD.(io.Closer); ok && o.sharedLock == nil {
+		closers = append(closers, closer)
+	}
+
+	mk, err = New(confFD, lockFD, snippetsFS, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	mk.closers = closers
+
+	if _, ok := root.(*osFilesystem); ok {
+		mk.root = o.root
+	}
+
+	return mk, nil
+}
+
+// openLockFile opens the lock file on the root, or returns the workspace one if
+// shared
+func openLockFile(root billy.Filesystem, o *options) (File, error) {
+	if o.sharedLock != nil {
+		return o.sharedLock.file, nil
+	}
+
+	// check if the maker lock file is valid
+	info, err := root.Stat(o.lockFilename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil && !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a valid Maker lock file", o.lockFilename)
 	}
-	lockFD.Lock()
-	runtime.SetFinalizer(lockFD, closeDescriptor)
 
-	return New(confFD, lockFD, snippetsFS)
+	// open the locker file for usage
+	return openAtomicFile(root, o.lockFilename)
 }
 
 // New returns an instance of Maker using the provided file descriptors to read
 // and write data from, and a target directory to manage the snippets within.
+// Repositories are only fetched when an operation needs them.
 //
 // The caller is responsible for closing both file descriptors
-func New(conf, lock File, directory billy.Filesystem) (mk *Maker, err error) {
+func New(conf, lock File, directory billy.Filesystem, opts ...Option) (mk *Maker, err error) {
+	o := newOptions(opts)
+
 	mk = &Maker{
-		configFile: conf,
-		lockFile:   lock,
-		directory:  directory,
-		lock:       make(Lock),
+		configFile:  conf,
+		lockFile:    lock,
+		directory:   directory,
+		lock:        NewLock(),
+		pending:     make(map[string][]byte),
+		logger:      o.logger,
+		subscribers: o.subscribers,
+
+		snippetsDirectory: o.snippetsDirectory,
+		configFilename:    o.configFilename,
+		lockFilename:      o.lockFilename,
+		repositoryFactory: o.repositoryFactory,
+		httpClient:        o.httpClient,
+		project:           o.project,
+		offline:           o.offline,
+		user:              o.userConfig,
+		cacheDirectory:    o.cacheDirectory,
+		jobs:              o.jobs,
+		upstream:          make(map[string][]byte),
 	}
 
-	err = unmarshalInto(conf, &mk.conf)
-	if err != nil {
-		return nil, err
+	if mk.jobs < 1 {
+		mk.jobs = 1
+	}
+
+	if mk.user == nil {
+		mk.user = &UserConfig{}
+	}
+
+	if mk.cacheDirectory == "" {
+		mk.cacheDirectory, err = expandHome(mk.user.CacheDirectory)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	err = unmarshalInto(lock, &mk.lock)
+	err = unmarshalInto(conf, &mk.conf)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, repository := range mk.conf.Repositories {
-		err = repository.Init()
+	if o.sharedLock != nil {
+		mk.lock = o.sharedLock.lock
+		mk.shared = o.sharedLock
+	} else {
+		err = unmarshalInto(lock, mk.lock)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	// fill in the data that older lock schemas do not have
+	for _, repository := range mk.conf.Repositories {
+		for name, pin := range repository.Snippets {
+			if pin.bare {
+				mk.logger.Warn("snippet pinned to a bare branch name, which is deprecated", "snippet", name, "pin", pin.Reference, "replacement", pin.String())
+			}
+
+			entry := mk.lock.Get(repository.URL, name)
+			if entry == nil {
+				continue
+			}
+
+			if entry.Constraint == "" {
+				entry.Constraint = pin.String()
+			}
+
+			if entry.Path == "" {
+				entry.Path = mk.snippetPath(name)
+			}
+		}
+	}
+
 	return mk, nil
 }
 
+// Close releases the process lock and file descriptors acquired by NewDefault.
+// It is safe to call multiple times
+func (mk *Maker) Close() error {
+	closers := mk.closers
+	mk.closers = nil
+
+	return closeAll(closers)
+}
+
+// closeAll closes all closers in reverse order, returning the first error
+func closeAll(closers []io.Closer) error {
+	var err error
+
+	for index := len(closers) - 1; index >= 0; index-- {
+		closeErr := closers[index].Close()
+		if closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
 // Init creates an empty configuration data with the default repository
 func (mk *Maker) Init() error {
+	return mk.InitContext(context.Background())
+}
+
+// InitContext is like Init, stopping if the context is done, in which case
+// nothing is written
+func (mk *Maker) InitContext(ctx context.Context) (err error) {
+	defer mk.restoreOnError(mk.snapshot(), &err)
+
 	if len(mk.conf.Repositories) > 0 {
 		return fmt.Errorf("maker is already initialized")
 	}
 
-	err := mk.conf.AddRepository(&Repository{
+	repository := &Repository{
 		Alias:    "wwmoraes",
 		URL:      "https://github.com/wwmoraes/maker-snippets.git",
-		Snippets: make(map[string]string),
-	})
+		Snippets: make(map[string]Pin),
+	}
+
+	if mk.user.DefaultRepository != "" {
+		userRepository := findRepository(mk.user.Repositories, mk.user.DefaultRepository)
+		if userRepository == nil {
+			return fmt.Errorf("default repository %s not found on the user configuration", mk.user.DefaultRepository)
+		}
+
+		repository = copyUserRepository(userRepository)
+	}
+
+	// fetch it as any other repository, so mirrors apply
+	err = mk.initRepository(ctx, repository)
 	if err != nil {
 		return err
 	}
 
-	return mk.Sync()
+	mk.conf.Repositories = append(mk.conf.Repositories, repository)
+
+	return mk.sync(ctx)
 }
 
 // Add fetches a snippet file and adds its info into the config and lock files
 func (mk *Maker) Add(name string) error {
+	return mk.AddContext(context.Background(), name)
+}
+
+// AddContext is like Add, stopping if the context is done, in which case
+// nothing is written
+func (mk *Maker) AddContext(ctx context.Context, name string) (err error) {
+	defer mk.restoreOnError(mk.snapshot(), &err)
+
 	name, versionStr, found := strings.Cut(name, "@")
 	if !found {
 		versionStr = "*"
@@ -180,7 +406,12 @@ func (mk *Maker) Add(name string) error {
 		alias = ""
 	}
 
-	repository, err := mk.conf.GetRepository(alias)
+	repository, copied, err := mk.addRepository(alias)
+	if err != nil {
+		return err
+	}
+
+	err = mk.initRepository(ctx, repository)
 	if err != nil {
 		return err
 	}
@@ -189,188 +420,1158 @@ func (mk *Maker) Add(name string) error {
 		return fmt.Errorf("snippet %s already added", name)
 	}
 
-	constraint, err := semver.NewConstraint(versionStr)
-	if err != nil && !errors.Is(err, semver.ErrInvalidVersion) {
+	scheme, err := repository.VersionScheme()
+	if err != nil {
 		return err
 	}
 
-	versions := make([]string, 0)
-	// no constraint found, passthrough branch/tag name directly
-	if constraint == nil {
-		fmt.Printf("DEBUG: %s isn't a valid constraint - using as tag/branch name directly\n", versionStr)
-		versions = append(versions, versionStr)
-	} else {
-		fmt.Printf("DEBUG: %s is a valid constraint - checking matching versions\n", versionStr)
+	pin, err := ParsePin(versionStr, scheme)
+	if err != nil {
+		return err
+	}
 
-		refs, err := repository.References()
+	if mk.shared != nil {
+		err = mk.shared.checkPin(repository.URL, name, pin)
 		if err != nil {
 			return err
 		}
+	}
 
-		err = refs.ForEach(func(r *plumbing.Reference) error {
-			name := r.Name()
-			if !(name.IsBranch() || name.IsTag()) {
-				return nil
-			}
+	entry, err := mk.resolve(repository, name, pin)
+	if err != nil {
+		return err
+	}
 
-			fmt.Printf("DEBUG: checking ref %s = %s\n", name.Short(), versionStr)
+	available, err := repository.Source.Snippets(entry.Commit)
+	if err != nil {
+		return err
+	}
 
-			version, err := semver.NewVersion(name.Short())
-			if err != nil {
-				fmt.Printf("DEBUG: %s not a semver\n", name.Short())
-				return nil
-			}
+	index := sort.SearchStrings(available, name)
+	if index == len(available) || available[index] != name {
+		return fmt.Errorf("snippet %s not found on %s, available snippets are %s", name, repository.URL, strings.Join(available, ", "))
+	}
 
-			if constraint.Match(version, false) {
-				versions = append(versions, version.String())
-				return nil
-				// return storer.ErrStop
-			}
+	_, err = mk.installSnippet(repository, name, nil, entry, StrategyTheirs)
+	if err != nil {
+		return err
+	}
 
-			return nil
-		})
-		if err != nil && errors.Is(err, storer.ErrStop) {
-			return err
+	repository.SetSnippet(name, pin)
+	mk.lock.Set(repository.URL, name, entry)
+
+	if copied {
+		mk.conf.Repositories = append(mk.conf.Repositories, repository)
+	}
+
+	return mk.sync(ctx)
+}
+
+// addRepository returns the repository to add snippets from, by alias or URL,
+// or the default one if the reference is empty. Repositories found on the user
+// configuration only are copied, and must be added to the project one
+func (mk *Maker) addRepository(reference string) (*Repository, bool, error) {
+	if reference == "" {
+		reference = mk.user.DefaultRepository
+	}
+
+	if reference == "" {
+		if len(mk.conf.Repositories) > 0 {
+			return mk.conf.Repositories[0], false, nil
+		}
+
+		if len(mk.user.Repositories) == 0 {
+			return nil, false, fmt.Errorf("no repositories configured")
 		}
+
+		reference = mk.user.Repositories[0].URL
 	}
 
-	if len(versions) == 0 {
-		return fmt.Errorf("no matching version or branch found")
+	repository := findRepository(mk.conf.Repositories, reference)
+	if repository != nil {
+		return repository, false, nil
 	}
 
-	sort.Strings(versions)
-	hash, err := repository.ResolveRevision(plumbing.Revision(versions[0]))
-	if err != nil {
-		return err
+	userRepository := findRepository(mk.user.Repositories, reference)
+	if userRepository == nil {
+		return nil, false, fmt.Errorf("repository %s not found", reference)
 	}
 
-	repository.SetSnippet(name, versionStr)
-	mk.lock.Set(repository.URL, name, hash.String())
+	// the project may have it under another alias
+	repository = findRepository(mk.conf.Repositories, userRepository.URL)
+	if repository != nil {
+		return repository, false, nil
+	}
 
-	fmt.Println("installing", color.MagentaString(name))
-	// TODO install newly added snippet
+	return copyUserRepository(userRepository), true, nil
+}
 
-	return mk.Sync()
+// copyUserRepository returns a project repository with the user repository
+// settings, except for the auth that only applies to the user
+func copyUserRepository(repository *Repository) *Repository {
+	return &Repository{
+		Alias:    repository.Alias,
+		URL:      repository.URL,
+		Scheme:   repository.Scheme,
+		Snippets: make(map[string]Pin),
+	}
 }
 
 // Remove removes a snippet file and its info from the config and lock files
 func (mk *Maker) Remove(name string) error {
+	return mk.RemoveContext(context.Background(), name)
+}
+
+// RemoveContext is like Remove, stopping if the context is done, in which case
+// nothing is written
+func (mk *Maker) RemoveContext(ctx context.Context, name string) (err error) {
+	defer mk.restoreOnError(mk.snapshot(), &err)
+
 	for _, repository := range mk.conf.Repositories {
 		delete(repository.Snippets, name)
-		mk.lock.Unset(repository.URL, name)
+
+		if mk.shared == nil || len(mk.shared.pins(repository.URL, name)) == 0 {
+			mk.lock.Unset(repository.URL, name)
+		}
 	}
 
-	fmt.Println("removed", color.MagentaString(name))
+	mk.emit(Event{Kind: EventRemoved, Snippet: name})
 
-	return mk.Sync()
+	return mk.sync(ctx)
 }
 
 // Install fetches the snippets if they're not present, or if there's any local
-// changes
-func (mk *Maker) Install(force bool) (err error) {
-	for _, repository := range mk.conf.Repositories {
-		for name, version := range repository.Snippets {
-			lockVersion := mk.lock.Get(repository.URL, name)
-			if lockVersion == "" {
-				lockVersion = version
-				// TODO generate lock on install
-			}
+// changes, which are handled with the merge strategy. Snippets whose pin
+// changed since they were locked are resolved again
+func (mk *Maker) Install(force bool, strategy MergeStrategy) (err error) {
+	return mk.InstallContext(context.Background(), force, strategy)
+}
+
+// InstallContext is like Install, stopping if the context is done, in which
+// case nothing is written
+func (mk *Maker) InstallContext(ctx context.Context, force bool, strategy MergeStrategy) (err error) {
+	defer mk.restoreOnError(mk.snapshot(), &err)
+
+	err = mk.initRepositories(ctx)
+	if err != nil {
+		return err
+	}
+
+	tasks := mk.snippetTasks(nil)
+
+	entries, err := mk.resolveTasks(ctx, tasks, func(task snippetTask, entry *LockEntry) bool {
+		return entry == nil || entry.Constraint != task.pin.String() || isStale(task.repository, entry)
+	})
+	if err != nil {
+		return err
+	}
 
-			file, err := repository.Get(version, name)
+	// new snippet files are taken as local contents if they exist already
+	previous := make([]*LockEntry, len(tasks))
+	for index, task := range tasks {
+		previous[index] = mk.lock.Get(task.repository.URL, task.name)
+		if previous[index] == nil {
+			previous[index] = entries[index]
+		}
+	}
+
+	return mk.installTasks(ctx, tasks, previous, entries, strategy)
+}
+
+// resolveTasks resolves the snippets whose lock entry needs it, in concurrent
+// jobs, and fetches the contents of all of them. It returns the entries to
+// install, which are not set on the lock yet
+func (mk *Maker) resolveTasks(ctx context.Context, tasks []snippetTask, needsResolve func(task snippetTask, entry *LockEntry) bool) ([]*LockEntry, error) {
+	entries := make([]*LockEntry, len(tasks))
+
+	err := mk.forEach(ctx, len(tasks), func(index int) error {
+		task := tasks[index]
+
+		entry := mk.lock.Get(task.repository.URL, task.name)
+		if needsResolve(task, entry) {
+			var err error
+
+			entry, err = mk.resolve(task.repository, task.name, task.pin)
 			if err != nil {
-				return err
+				return fmt.Errorf("snippet %s: %w", task.name, err)
 			}
+		} else if entry.Hash == "" {
+			var err error
 
-			installed, err := mk.install(name, file)
+			entry, err = mk.completeEntry(task.repository, task.name, task.pin, entry)
 			if err != nil {
-				return err
+				return fmt.Errorf("snippet %s: %w", task.name, err)
 			}
+		}
 
-			if installed {
-				fmt.Println("updated ", color.MagentaString(name))
-			} else {
-				fmt.Println("skipped ", color.MagentaString(name))
-			}
+		entries[index] = entry
+
+		_, err := mk.upstreamContents(task.repository, task.name, entry.Commit)
+		if err != nil {
+			return fmt.Errorf("snippet %s: %w", task.name, err)
 		}
-	}
 
-	return mk.Sync()
+		return nil
+	})
+
+	return entries, err
 }
 
-// Sync marshals the current configuration and lock data back into their
-// respective file handlers. Flushing/syncing to an underlying persistent media
-// is the caller's responsibility.
-func (mk *Maker) Sync() (err error) {
-	err = mk.configFile.Truncate(0)
-	if err != nil {
-		return err
+// installTasks locks and installs the snippets in order, and writes all changes
+// if every snippet is installed. Errors are reported for all snippets at once
+func (mk *Maker) installTasks(ctx context.Context, tasks []snippetTask, previous, entries []*LockEntry, strategy MergeStrategy) error {
+	conflicts := make([]string, 0)
+	errs := make([]error, 0)
+
+	for index, task := range tasks {
+		mk.lock.Set(task.repository.URL, task.name, entries[index])
+
+		conflict, err := mk.installSnippet(task.repository, task.name, previous[index], entries[index], strategy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("snippet %s: %w", task.name, err))
+			continue
+		}
+
+		if conflict {
+			conflicts = append(conflicts, task.name)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 
-	_, err = mk.configFile.Seek(0, 0)
+	err := mk.sync(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = marshalInto(&mk.conf, mk.configFile)
+	return conflictsError(conflicts)
+}
+
+// InstallFrozen fetches the snippets exactly as locked, failing if the lock is
+// missing entries for any configured snippet, has entries not present on the
+// configuration or would change in any way. Neither the configuration nor the
+// lock files are written, and local changes are handled with the merge
+// strategy
+func (mk *Maker) InstallFrozen(strategy MergeStrategy) (err error) {
+	return mk.InstallFrozenContext(context.Background(), strategy)
+}
+
+// InstallFrozenContext is like InstallFrozen, stopping if the context is done,
+// in which case nothing is written
+func (mk *Maker) InstallFrozenContext(ctx context.Context, strategy MergeStrategy) (err error) {
+	defer mk.restoreOnError(mk.snapshot(), &err)
+
+	err = mk.initRepositories(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = mk.lockFile.Truncate(0)
+	tasks := mk.snippetTasks(nil)
+	entries := make([]*LockEntry, len(tasks))
+
+	err = mk.forEach(ctx, len(tasks), func(index int) error {
+		task := tasks[index]
+
+		entry := mk.lock.Get(task.repository.URL, task.name)
+		if entry == nil {
+			return fmt.Errorf("%s is missing an entry for snippet %s", mk.lockFilename, task.name)
+		}
+
+		if entry.Constraint != task.pin.String() {
+			return fmt.Errorf("%s entry for snippet %s is locked to %s instead of %s", mk.lockFilename, task.name, entry.Constraint, task.pin.String())
+		}
+
+		if isStale(task.repository, entry) {
+			return fmt.Errorf("%s entry for snippet %s is out of date", mk.lockFilename, task.name)
+		}
+
+		expected := *entry
+
+		_, err := mk.snippetContents(task.repository, task.name, &expected)
+		if err != nil {
+			return fmt.Errorf("snippet %s: %w", task.name, err)
+		}
+
+		if expected.Hash != entry.Hash || expected.Patched != entry.Patched || entry.Path != mk.snippetPath(task.name) {
+			return fmt.Errorf("%s entry for snippet %s is out of date", mk.lockFilename, task.name)
+		}
+
+		entries[index] = entry
+
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	_, err = mk.lockFile.Seek(0, 0)
+	// shared locks are checked as a whole by the workspace
+	if mk.shared == nil {
+		for url, snippets := range mk.lock.Repositories {
+			repository, err := mk.conf.GetRepository(url)
+			if err != nil {
+				return fmt.Errorf("%s has entries for repository %s not present on %s", mk.lockFilename, url, mk.configFilename)
+			}
+
+			for name := range snippets {
+				if !repository.HasSnippet(name) {
+					return fmt.Errorf("%s has an entry for snippet %s not present on %s", mk.lockFilename, name, mk.configFilename)
+				}
+			}
+		}
+	}
+
+	// catches any remaining difference, such as schema migrations
+	changed, err := mk.lockChanged()
 	if err != nil {
 		return err
 	}
 
-	err = marshalInto(&mk.lock, mk.lockFile)
+	if changed {
+		return fmt.Errorf("%s needs to be updated", mk.lockFilename)
+	}
+
+	conflicts := make([]string, 0)
+
+	for index, task := range tasks {
+		conflict, err := mk.installSnippet(task.repository, task.name, entries[index], entries[index], strategy)
+		if err != nil {
+			return err
+		}
+
+		if conflict {
+			conflicts = append(conflicts, task.name)
+		}
+	}
+
+	err = mk.commit(ctx)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return conflictsError(conflicts)
 }
 
-func (mk *Maker) install(name string, file FileReader) (bool, error) {
-	fd, err := mk.directory.OpenFile(fmt.Sprintf("%s.mk", name), os.O_RDWR|os.O_CREATE, 0640)
-	if err != nil {
-		return false, err
-	}
-	defer fd.Close()
+// Patch stores the local modifications of an installed snippet as a unified
+// diff against its locked upstream contents, which is applied whenever the
+// snippet is installed. The patch is removed if there are no modifications
+func (mk *Maker) Patch(name string) error {
+	return mk.PatchContext(context.Background(), name)
+}
 
-	currentData, err := io.ReadAll(fd)
+// PatchContext is like Patch, stopping if the context is done, in which case
+// nothing is written
+func (mk *Maker) PatchContext(ctx context.Context, name string) (err error) {
+	defer mk.restoreOnError(mk.snapshot(), &err)
+
+	repository, err := mk.conf.GetSnippetRepository(name)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	hash := plumbing.ComputeHash(plumbing.BlobObject, currentData)
-	if hash.String() == file.ID().String() {
-		return false, nil
+	entry := mk.lock.Get(repository.URL, name)
+	if entry == nil {
+		return fmt.Errorf("snippet %s is not installed", name)
 	}
 
-	reader, err := file.Reader()
+	currentData, exists, err := mk.readSnippet(name)
 	if err != nil {
-		return false, err
+		return err
 	}
-	defer reader.Close()
 
-	err = fd.Truncate(0)
-	if err != nil {
-		return false, err
+	if !exists {
+		return fmt.Errorf("snippet %s is not installed", name)
 	}
 
-	_, err = fd.Seek(0, 0)
+	err = mk.initRepository(ctx, repository)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	_, err = io.Copy(fd, reader)
+	upstreamData, err := mk.upstreamContents(repository, name, entry.Commit)
+	if err != nil {
+		return err
+	}
+
+	patch := diff.Unified(upstreamData, currentData, path.Join("a", snippetFilename(name)), path.Join("b", snippetFilename(name)), diff.DefaultContext)
+	mk.pending[patchFilename(name)] = patch
+
+	if patch == nil {
+		entry.Patched = ""
+
+		mk.emit(Event{Kind: EventUnpatched, Repository: repository.URL, Snippet: name})
+
+		return mk.sync(ctx)
+	}
+
+	entry.Patched = blobHash(currentData)
+
+	mk.emit(Event{Kind: EventPatched, Repository: repository.URL, Snippet: name})
+
+	return mk.sync(ctx)
+}
+
+// Update resolves the pins of the given snippets again, or all of them if none
+// is provided, and installs any changes. Version constraints move to the
+// highest matching version and branches follow their heads, while commit pins
+// are only moved if changed on the configuration. Local changes are handled
+// with the merge strategy
+func (mk *Maker) Update(strategy MergeStrategy, names ...string) (err error) {
+	return mk.UpdateContext(context.Background(), strategy, names...)
+}
+
+// UpdateContext is like Update, stopping if the context is done, in which case
+// nothing is written
+func (mk *Maker) UpdateContext(ctx context.Context, strategy MergeStrategy, names ...string) (err error) {
+	defer mk.restoreOnError(mk.snapshot(), &err)
+
+	filter := make(map[string]bool, len(names))
+	for _, name := range names {
+		_, err = mk.conf.GetSnippetRepository(name)
+		if err != nil {
+			return err
+		}
+
+		filter[name] = true
+	}
+
+	err = mk.initRepositories(ctx)
+	if err != nil {
+		return err
+	}
+
+	tasks := mk.snippetTasks(filter)
+
+	entries, err := mk.resolveTasks(ctx, tasks, func(task snippetTask, entry *LockEntry) bool {
+		return task.pin.Kind != PinCommit || entry == nil || entry.Constraint != task.pin.String()
+	})
+	if err != nil {
+		return err
+	}
+
+	previous := make([]*LockEntry, len(tasks))
+	for index, task := range tasks {
+		previous[index] = mk.lock.Get(task.repository.URL, task.name)
+	}
+
+	return mk.installTasks(ctx, tasks, previous, entries, strategy)
+}
+
+// conflictsError returns an error listing the snippets with merge conflicts,
+// if there's any
+func conflictsError(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+
+	return fmt.Errorf("merge conflicts on snippets %s, resolve them and install again", strings.Join(names, ", "))
+}
+
+// initRepository fetches the repository if needed, through its mirror if any,
+// reporting its progress, or opens its vendored snippets if offline
+func (mk *Maker) initRepository(ctx context.Context, repository *Repository) error {
+	if repository.Source != nil {
+		return nil
+	}
+
+	url := rewriteURL(repository.URL, mk.conf.Mirrors, mk.user.Mirrors)
+	if url != repository.URL {
+		mk.logger.Debug("rewriting repository URL", "url", repository.URL, "mirror", url)
+	}
+
+	if mk.offline && !isLocalSource(url) {
+		source, err := mk.vendorSource(repository.URL)
+		if err != nil {
+			return err
+		}
+
+		repository.Source = source
+		return nil
+	}
+
+	mk.emit(Event{Kind: EventCloning, Repository: repository.URL})
+
+	auth := repository.Auth
+	if auth != nil && !sameOrigin(repository.URL, url) {
+		mk.logger.Debug("ignoring repository auth on a mirror on another host", "url", repository.URL, "mirror", url)
+		auth = nil
+	}
+
+	if auth == nil {
+		auth = mk.user.auth(repository, url)
+	}
+
+	err := repository.initFrom(ctx, url, auth, mk.sourceFactory(), &progressWriter{mk: mk, repository: repository.URL})
+	if err != nil {
+		return err
+	}
+
+	mk.emit(Event{Kind: EventCloned, Repository: repository.URL})
+
+	return nil
+}
+
+// sourceFactory returns the repository factory with the HTTP client and cache
+// directory, and with relative local repository paths based on the project
+// directory instead of the current one
+func (mk *Maker) sourceFactory() RepositoryFactory {
+	return func(options FetchOptions) (Source, error) {
+		if mk.root != "" {
+			options.URL = absoluteSourceURL(options.URL, mk.root)
+		}
+
+		options.HTTPClient = mk.httpClient
+		options.CacheDirectory = mk.cacheDirectory
+
+		// git remotes only use it once UseGitHTTPClients is called
+		if mk.httpClient != nil {
+			options.Context = withHTTPClient(options.ctx(), mk.httpClient)
+		}
+
+		return mk.repositoryFactory(options)
+	}
+}
+
+// resolve returns a lock entry with the commit that the snippet pin currently
+// refers to
+func (mk *Maker) resolve(repository *Repository, name string, pin Pin) (*LockEntry, error) {
+	mk.emit(Event{Kind: EventResolving, Repository: repository.URL, Snippet: name, Pin: pin.String()})
+
+	entry, err := mk.resolvePin(repository, name, pin)
+	if err != nil {
+		return nil, err
+	}
+
+	mk.emit(Event{
+		Kind:       EventResolved,
+		Repository: repository.URL,
+		Snippet:    name,
+		Pin:        pin.String(),
+		Version:    entry.Version,
+		Commit:     entry.Commit,
+	})
+
+	return entry, nil
+}
+
+// resolvePin returns a lock entry with the revision that the snippet pin
+// currently refers to
+func (mk *Maker) resolvePin(repository *Repository, name string, pin Pin) (*LockEntry, error) {
+	entry := &LockEntry{
+		Constraint: pin.String(),
+	}
+
+	if source, ok := repository.Source.(UnversionedSource); ok {
+		return resolveUnversioned(repository, source, pin, entry)
+	}
+
+	if pin.Kind == PinVersion {
+		return mk.resolveConstraint(repository, name, pin.Constraint, entry)
+	}
+
+	id, err := repository.Resolve(name, pin.Kind, pin.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if pin.Kind != PinCommit {
+		entry.Tag = pin.Reference
+	}
+
+	entry.Commit = id
+
+	// commits are identified by their pseudo-versions on Go module versioning
+	source, dated := repository.Source.(DatedSource)
+	if pin.Kind == PinCommit && dated && repository.Scheme == semver.SchemePseudo {
+		scheme, err := repository.VersionScheme()
+		if err != nil {
+			return nil, err
+		}
+
+		version, err := pseudoVersion(source, id)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Version = scheme.Format(version)
+	}
+
+	mk.logger.Debug("resolved pin", "repository", repository.URL, "pin", pin.String(), "commit", entry.Commit)
+
+	return entry, nil
+}
+
+// resolveUnversioned fills the lock entry with the current revision of a
+// source without versions, which only accepts the any version constraint
+func resolveUnversioned(repository *Repository, source UnversionedSource, pin Pin, entry *LockEntry) (*LockEntry, error) {
+	if pin.Kind != PinVersion || pin.Constraint.String() != "*" {
+		return nil, fmt.Errorf("%s has no versions, pin its snippets with * instead of %s", repository.URL, pin)
+	}
+
+	id, err := source.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Commit = id
+
+	return entry, nil
+}
+
+// completeEntry returns a copy of an entry migrated from the first lock schema,
+// which only has the commit, with the version and tag it comes from filled in.
+// The commit is kept as locked, even if the pin refers to a newer one now
+func (mk *Maker) completeEntry(repository *Repository, name string, pin Pin, entry *LockEntry) (*LockEntry, error) {
+	completed := *entry
+
+	switch {
+	case pin.Kind == PinBranch || pin.Kind == PinTag:
+		completed.Tag = pin.Reference
+	case pin.Kind == PinVersion && !repository.IsUnversioned():
+		scheme, err := repository.VersionScheme()
+		if err != nil {
+			return nil, err
+		}
+
+		names, err := repository.Versions(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var latest semver.Version
+
+		for _, tag := range names {
+			version, err := scheme.ParseVersion(tag)
+			if err != nil || !pin.Constraint.Match(version, false) {
+				continue
+			}
+
+			if latest != nil && scheme.Compare(latest, version) <= 0 {
+				continue
+			}
+
+			id, err := repository.Resolve(name, PinVersion, tag)
+			if err != nil {
+				return nil, err
+			}
+
+			if id == entry.Commit {
+				latest = version
+				completed.Version = scheme.Format(version)
+				completed.Tag = tag
+			}
+		}
+	}
+
+	mk.logger.Debug("completed migrated lock entry", "repository", repository.URL, "snippet", name, "version", completed.Version, "tag", completed.Tag)
+
+	return &completed, nil
+}
+
+// isStale returns true if the entry is locked to a revision of a source
+// without versions that is no longer current, as its contents changed since
+func isStale(repository *Repository, entry *LockEntry) bool {
+	source, ok := repository.Source.(UnversionedSource)
+	if !ok {
+		return false
+	}
+
+	current, err := source.Current()
+
+	return err == nil && current != entry.Commit
+}
+
+// resolveConstraint fills the lock entry with the highest snippet version that
+// satisfies the constraint
+func (mk *Maker) resolveConstraint(repository *Repository, snippet string, constraint semver.Constraint, entry *LockEntry) (*LockEntry, error) {
+	mk.logger.Debug("resolving version constraint", "repository", repository.URL, "constraint", constraint.String())
+
+	scheme, err := repository.VersionScheme()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := repository.Versions(snippet)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		latest     semver.Version
+		latestName string
+	)
+
+	for _, name := range names {
+		version, err := scheme.ParseVersion(name)
+		if err != nil {
+			mk.logger.Debug("skipping reference", "ref", name, "reason", fmt.Sprintf("not a %s version", scheme.Name()))
+			continue
+		}
+
+		if !constraint.Match(version, false) {
+			mk.logger.Debug("skipping reference", "ref", name, "reason", "does not match the constraint")
+			continue
+		}
+
+		if latest == nil || scheme.Compare(latest, version) > 0 {
+			latest = version
+			latestName = name
+		}
+	}
+
+	if latest == nil {
+		return resolvePseudoVersion(repository, snippet, scheme, constraint, entry)
+	}
+
+	id, err := repository.Resolve(snippet, PinVersion, latestName)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Version = scheme.Format(latest)
+	entry.Tag = latestName
+	entry.Commit = id
+
+	mk.logger.Debug("resolved version constraint", "repository", repository.URL, "constraint", constraint.String(), "version", entry.Version, "commit", entry.Commit)
+
+	return entry, nil
+}
+
+// resolvePseudoVersion fills the lock entry with an untagged revision, either
+// pinned by its pseudo-version or, on Go module versioning, the default branch
+// head if the constraint allows it
+func resolvePseudoVersion(repository *Repository, snippet string, scheme semver.VersionScheme, constraint semver.Constraint, entry *LockEntry) (*LockEntry, error) {
+	source, dated := repository.Source.(DatedSource)
+
+	version, err := scheme.ParseVersion(strings.TrimPrefix(constraint.String(), "="))
+	if err == nil && semver.IsPseudoVersion(version) {
+		revision, err := semver.PseudoVersionRevision(version)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := repository.Resolve(snippet, PinCommit, revision)
+		if err != nil {
+			return nil, err
+		}
+
+		if dated {
+			err = checkPseudoVersionTime(source, scheme, version, id)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		entry.Version = scheme.Format(version)
+		entry.Commit = id
+
+		return entry, nil
+	}
+
+	if !dated || scheme.Name() != semver.SchemePseudo {
+		return nil, fmt.Errorf("no matching version found for %s", constraint)
+	}
+
+	id, err := source.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	version, err = pseudoVersion(source, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !constraint.Match(version, true) {
+		return nil, fmt.Errorf("no matching version found for %s", constraint)
+	}
+
+	entry.Version = scheme.Format(version)
+	entry.Commit = id
+
+	return entry, nil
+}
+
+// pseudoVersion returns the Go module pseudo-version of a commit
+func pseudoVersion(source DatedSource, id string) (semver.Version, error) {
+	commitTime, err := source.CommitTime(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return semver.NewPseudoVersion(commitTime, id)
+}
+
+// checkPseudoVersionTime fails if the pseudo-version timestamp is not the time
+// its commit was made at
+func checkPseudoVersionTime(source DatedSource, scheme semver.VersionScheme, version semver.Version, id string) error {
+	versionTime, err := semver.PseudoVersionTime(version)
+	if err != nil {
+		return err
+	}
+
+	commitTime, err := source.CommitTime(id)
+	if err != nil {
+		return err
+	}
+
+	if !versionTime.Equal(commitTime.UTC().Truncate(time.Second)) {
+		return fmt.Errorf("pseudo-version %s does not match the commit time %s of %s", scheme.Format(version), commitTime.UTC().Format(time.RFC3339), id)
+	}
+
+	return nil
+}
+
+// Sync writes the pending snippet file changes along with the current
+// configuration and lock data as a single transaction. Files are replaced
+// atomically when supported, and if any write fails all files written so far
+// are restored to their previous contents
+func (mk *Maker) Sync() (err error) {
+	return mk.sync(context.Background())
+}
+
+// sync is like Sync, writing nothing if the context is done
+func (mk *Maker) sync(ctx context.Context) (err error) {
+	var confData, lockData bytes.Buffer
+
+	err = marshalInto(&mk.conf, &confData)
+	if err != nil {
+		return err
+	}
+
+	err = marshalInto(mk.lock, &lockData)
+	if err != nil {
+		return err
+	}
+
+	return mk.commit(ctx,
+		fileContents{mk.lockFile, lockData.Bytes()},
+		fileContents{mk.configFile, confData.Bytes()},
+	)
+}
+
+// fileContents pairs a file descriptor with the contents to replace it with
+type fileContents struct {
+	file File
+	data []byte
+}
+
+// commit writes the pending snippet file changes and then replaces the given
+// files contents, restoring every written file if any step fails. Nothing is
+// written if the context is done. Each file is replaced atomically, but the set
+// is only restored on failures reported as errors: a crash midway may leave
+// snippets newer than the lock, which Verify reports as modified
+func (mk *Maker) commit(ctx context.Context, files ...fileContents) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx := &transaction{}
+
+	filenames := make([]string, 0, len(mk.pending))
+	for filename := range mk.pending {
+		filenames = append(filenames, filename)
+	}
+
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		mk.logger.Debug("writing file", "filename", filename)
+
+		err := tx.writeFile(mk.directory, filename, mk.pending[filename])
+		if err != nil {
+			return tx.abort(err)
+		}
+	}
+
+	for _, file := range files {
+		err := tx.replaceFile(file.file, file.data)
+		if err != nil {
+			return tx.abort(err)
+		}
+	}
+
+	mk.pending = make(map[string][]byte)
+	mk.commits++
+
+	return nil
+}
+
+// snapshot is a copy of the configuration, lock and pending changes of an
+// instance
+type snapshot struct {
+	commits      int
+	repositories []*Repository
+	snippets     []map[string]Pin
+	lock         map[string]map[string]LockEntry
+	pending      map[string][]byte
+}
+
+// snapshot returns a copy of the current state, to be restored if an
+// operation fails
+func (mk *Maker) snapshot() *snapshot {
+	state := &snapshot{
+		commits:      mk.commits,
+		repositories: append([]*Repository{}, mk.conf.Repositories...),
+		snippets:     make([]map[string]Pin, len(mk.conf.Repositories)),
+		lock:         make(map[string]map[string]LockEntry, len(mk.lock.Repositories)),
+		pending:      make(map[string][]byte, len(mk.pending)),
+	}
+
+	for index, repository := range mk.conf.Repositories {
+		state.snippets[index] = make(map[string]Pin, len(repository.Snippets))
+		for name, pin := range repository.Snippets {
+			state.snippets[index][name] = pin
+		}
+	}
+
+	for url, snippets := range mk.lock.Repositories {
+		state.lock[url] = make(map[string]LockEntry, len(snippets))
+		for name, entry := range snippets {
+			state.lock[url][name] = *entry
+		}
+	}
+
+	for filename, data := range mk.pending {
+		state.pending[filename] = data
+	}
+
+	return state
+}
+
+// restoreOnError restores the state of the snapshot if the operation failed
+// without committing, so that no partial changes are written later. It is
+// meant to be deferred with the operation error
+func (mk *Maker) restoreOnError(state *snapshot, err *error) {
+	if *err == nil || mk.commits != state.commits {
+		return
+	}
+
+	mk.conf.Repositories = state.repositories
+	for index, repository := range state.repositories {
+		repository.Snippets = state.snippets[index]
+	}
+
+	mk.lock.Repositories = make(map[string]map[string]*LockEntry, len(state.lock))
+	for url, snippets := range state.lock {
+		for name, entry := range snippets {
+			entry := entry
+			mk.lock.Set(url, name, &entry)
+		}
+	}
+
+	mk.pending = state.pending
+}
+
+// lockChanged returns true if the current lock data differs from the lock file
+// contents
+func (mk *Maker) lockChanged() (bool, error) {
+	_, err := mk.lockFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return false, err
+	}
+
+	currentData, err := io.ReadAll(mk.lockFile)
+	if err != nil {
+		return false, err
+	}
+
+	var data bytes.Buffer
+
+	err = marshalInto(mk.lock, &data)
+	if err != nil {
+		return false, err
+	}
+
+	return !bytes.Equal(currentData, data.Bytes()), nil
+}
+
+// installSnippet writes the snippet file contents found at the locked commit,
+// with the local patch applied if any, and records its hashes and path on the
+// entry. Local modifications made on top of the previous entry contents are
+// handled with the strategy. It returns true if the snippet file was written
+// with merge conflicts
+func (mk *Maker) installSnippet(repository *Repository, name string, previous, entry *LockEntry, strategy MergeStrategy) (bool, error) {
+	var (
+		previousData []byte
+		err          error
+	)
+
+	mk.emit(Event{Kind: EventInstalling, Repository: repository.URL, Snippet: name, Commit: entry.Commit})
+
+	if previous != nil && isStale(repository, previous) {
+		// sources without versions only have their current contents, so the
+		// previous ones are known only if the snippet file still matches them
+		currentData, _, err := mk.readSnippet(name)
+		if err != nil {
+			return false, err
+		}
+
+		hash := blobHash(currentData)
+		if hash == previous.Hash || hash == previous.Patched {
+			previousData = currentData
+		}
+	} else if previous != nil {
+		base := *previous
+
+		previousData, err = mk.snippetContents(repository, name, &base)
+		if errors.Is(err, diff.ErrHunkFailed) {
+			// the patch may have been created after the previous contents
+			previousData, err = mk.snippetContents(repository, name, &LockEntry{Commit: previous.Commit})
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	upstreamData, err := mk.snippetContents(repository, name, entry)
+	if err != nil {
+		return false, err
+	}
+
+	entry.Path = mk.snippetPath(name)
+
+	currentData, exists, err := mk.readSnippet(name)
+	if err != nil {
+		return false, err
+	}
+
+	if exists && bytes.Equal(currentData, upstreamData) {
+		mk.emit(Event{Kind: EventSkipped, Repository: repository.URL, Snippet: name, Commit: entry.Commit})
+		return false, nil
+	}
+
+	modified := exists && previous != nil && !bytes.Equal(currentData, previousData)
+	if modified && strategy != StrategyTheirs && bytes.Equal(upstreamData, previousData) {
+		// there are no upstream changes to bring in
+		mk.emit(Event{Kind: EventKept, Repository: repository.URL, Snippet: name, Commit: entry.Commit})
+		return false, nil
+	}
+
+	if modified {
+		switch strategy {
+		case StrategyOurs:
+			mk.emit(Event{Kind: EventKept, Repository: repository.URL, Snippet: name, Commit: entry.Commit})
+			return false, nil
+		case StrategyFail:
+			return false, fmt.Errorf("snippet %s has local modifications", name)
+		case StrategyMerge:
+			mergedData, conflict := diff.Merge(previousData, currentData, upstreamData, diff.Labels{
+				Ours:   "local",
+				Base:   previous.Commit,
+				Theirs: entry.Commit,
+			})
+
+			if bytes.Equal(mergedData, currentData) {
+				mk.emit(Event{Kind: EventKept, Repository: repository.URL, Snippet: name, Commit: entry.Commit})
+				return false, nil
+			}
+
+			err = mk.writeSnippet(name, mergedData)
+			if err != nil {
+				return false, err
+			}
+
+			if conflict {
+				mk.emit(Event{Kind: EventConflict, Repository: repository.URL, Snippet: name, Commit: entry.Commit})
+			} else {
+				mk.emit(Event{Kind: EventMerged, Repository: repository.URL, Snippet: name, Commit: entry.Commit})
+			}
+
+			return conflict, nil
+		}
+	}
+
+	err = mk.writeSnippet(name, upstreamData)
 	if err != nil {
 		return false, err
 	}
 
-	return true, nil
+	mk.emit(Event{Kind: EventUpdated, Repository: repository.URL, Snippet: name, Commit: entry.Commit})
+
+	return false, nil
+}
+
+// snippetContents returns the snippet contents found at the entry commit, with
+// the local patch applied if any, and records the upstream and patched hashes
+// on the entry
+func (mk *Maker) snippetContents(repository *Repository, name string, entry *LockEntry) ([]byte, error) {
+	data, err := mk.upstreamContents(repository, name, entry.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Hash = blobHash(data)
+	entry.Patched = ""
+
+	patch, exists, err := mk.readPatch(name)
+	if err != nil || !exists {
+		return data, err
+	}
+
+	data, err = diff.Apply(data, patch)
+	if err != nil {
+		return nil, fmt.Errorf("patch %s no longer applies to snippet %s at %s: %w", mk.patchPath(name), name, entry.Commit, err)
+	}
+
+	entry.Patched = blobHash(data)
+
+	return data, nil
+}
+
+// snippetFilename returns the snippet file name within the snippets directory
+func snippetFilename(name string) string {
+	return fmt.Sprintf("%s.mk", name)
+}
+
+// snippetPath returns the snippet file path relative to the project root
+func (mk *Maker) snippetPath(name string) string {
+	return path.Join(mk.snippetsDirectory, snippetFilename(name))
+}
+
+// patchFilename returns the snippet patch file name within the snippets
+// directory
+func patchFilename(name string) string {
+	return path.Join(PatchesDirectory, fmt.Sprintf("%s.patch", name))
+}
+
+// patchPath returns the snippet patch file path relative to the project root
+func (mk *Maker) patchPath(name string) string {
+	return path.Join(mk.snippetsDirectory, patchFilename(name))
+}
+
+// readSnippet returns the snippet file contents, and false if it does not exist
+func (mk *Maker) readSnippet(name string) ([]byte, bool, error) {
+	return mk.readFile(snippetFilename(name))
+}
+
+// readPatch returns the snippet patch contents, and false if it does not exist
+func (mk *Maker) readPatch(name string) ([]byte, bool, error) {
+	return mk.readFile(patchFilename(name))
+}
+
+// readFile returns a snippets directory file contents, including pending
+// changes, and false if it does not exist
+func (mk *Maker) readFile(filename string) ([]byte, bool, error) {
+	data, staged := mk.pending[filename]
+	if staged {
+		return data, data != nil, nil
+	}
+
+	return readFilesystemFile(mk.directory, filename)
+}
+
+// writeSnippet sets the snippet file contents to be written on the next commit
+func (mk *Maker) writeSnippet(name string, data []byte) error {
+	if data == nil {
+		data = []byte{}
+	}
+
+	mk.pending[snippetFilename(name)] = data
+
+	return nil
 }
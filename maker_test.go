@@ -0,0 +1,359 @@
+package maker_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/wwmoraes/maker"
+)
+
+// snippetsRepository returns a factory for an in-memory repository with one
+// tagged commit per version of the snippet contents
+func snippetsRepository(t *testing.T, versions map[string]string) maker.RepositoryFactory {
+	t.Helper()
+
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		contents, exists := versions[version]
+		if !exists {
+			continue
+		}
+
+		err = util.WriteFile(worktree.Filesystem, "snippets/go.mk", []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = worktree.Add("snippets/go.mk")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := worktree.Commit(version, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = repo.CreateTag(version, hash, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := maker.NewGitSource(repo)
+
+	return func(options maker.FetchOptions) (maker.Source, error) {
+		return source, nil
+	}
+}
+
+// localSnippetsRepository creates an on-disk repository with one tagged commit
+// per version of the go snippet contents, and returns its path
+func localSnippetsRepository(t *testing.T, versions map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.MkdirAll(filepath.Join(dir, "snippets"), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		contents, exists := versions[version]
+		if !exists {
+			continue
+		}
+
+		err = os.WriteFile(filepath.Join(dir, "snippets", "go.mk"), []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = worktree.Add("snippets/go.mk")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := worktree.Commit(version, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = repo.CreateTag(version, hash, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// pinConfig returns a configuration with the go snippet pinned on the
+// repository
+func pinConfig(url, pin string) []byte {
+	return []byte("repositories:\n- alias: test\n  url: " + url + "\n  snippets:\n    go: '" + pin + "'\n")
+}
+
+// newProjectMaker returns a Maker for the configuration and lock files of the
+// project filesystem, with its snippets on the .make directory
+func newProjectMaker(t *testing.T, project billy.Filesystem, opts ...maker.Option) *maker.Maker {
+	t.Helper()
+
+	conf, err := project.OpenFile(maker.ConfFilename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := project.OpenFile(maker.LockFilename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directory, err := project.Chroot(maker.SnippetsDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mk, err := maker.New(conf, lock, directory, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mk
+}
+
+const testConfig = `repositories:
+- alias: test
+  url: https://example.com/snippets.git
+  snippets: {}
+`
+
+func TestNewWithOptions(t *testing.T) {
+	filesystem := memfs.New()
+
+	err := util.WriteFile(filesystem, "project/maker.yaml", []byte(testConfig), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mk, err := maker.NewWithOptions(
+		maker.WithFilesystem(filesystem),
+		maker.WithRoot("project"),
+		maker.WithSnippetsDirectory("snippets"),
+		maker.WithRepositoryFactory(snippetsRepository(t, map[string]string{
+			"1.0.0": "one\n",
+			"1.1.0": "one dot one\n",
+			"2.0.0": "two\n",
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mk.Close()
+
+	err = mk.Add("go@^1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := util.ReadFile(filesystem, "project/snippets/go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one dot one\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "one dot one\n")
+	}
+
+	lockData, err := util.ReadFile(filesystem, "project/maker.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"version: 1.1.0", "path: snippets/go.mk"} {
+		if !strings.Contains(string(lockData), want) {
+			t.Errorf("lock file does not contain %q:\n%s", want, lockData)
+		}
+	}
+
+	verification, err := mk.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !verification.OK() {
+		t.Errorf("got verification %+v, want no differences", verification)
+	}
+
+	err = util.WriteFile(filesystem, "project/snippets/go.mk", []byte("local\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err = mk.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(verification.Modified) != 1 || verification.Modified[0] != "go" {
+		t.Errorf("got modified snippets %v, want [go]", verification.Modified)
+	}
+}
+
+func TestNewWithMemoryFiles(t *testing.T) {
+	conf := maker.NewMemoryFile([]byte(testConfig))
+	lock := maker.NewMemoryFile(nil)
+
+	events := make([]maker.EventKind, 0)
+
+	mk, err := maker.New(conf, lock, memfs.New(),
+		maker.WithRepositoryFactory(snippetsRepository(t, map[string]string{
+			"1.0.0": "one\n",
+		})),
+		maker.WithSubscriber(maker.SubscriberFunc(func(event maker.Event) {
+			events = append(events, event.Kind)
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("test:go@1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(conf.Bytes(), []byte("go: =1.0.0")) {
+		t.Errorf("configuration does not contain the snippet:\n%s", conf.Bytes())
+	}
+
+	if !bytes.Contains(lock.Bytes(), []byte("tag: 1.0.0")) {
+		t.Errorf("lock does not contain the snippet:\n%s", lock.Bytes())
+	}
+
+	wantEvents := []maker.EventKind{
+		maker.EventCloning,
+		maker.EventCloned,
+		maker.EventResolving,
+		maker.EventResolved,
+		maker.EventInstalling,
+		maker.EventUpdated,
+	}
+
+	if len(events) != len(wantEvents) {
+		t.Fatalf("got events %v, want %v", events, wantEvents)
+	}
+
+	for index := range wantEvents {
+		if events[index] != wantEvents[index] {
+			t.Errorf("got events %v, want %v", events, wantEvents)
+			break
+		}
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	conf := maker.NewMemoryFile([]byte(testConfig))
+	lock := maker.NewMemoryFile(nil)
+	directory := memfs.New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// cancels once the snippet is about to be written, as an interrupt would
+	mk, err := maker.New(conf, lock, directory,
+		maker.WithRepositoryFactory(snippetsRepository(t, map[string]string{
+			"1.0.0": "one\n",
+		})),
+		maker.WithSubscriber(maker.SubscriberFunc(func(event maker.Event) {
+			if event.Kind == maker.EventUpdated {
+				cancel()
+			}
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.AddContext(ctx, "test:go@1.0.0")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+
+	if string(conf.Bytes()) != testConfig {
+		t.Errorf("got configuration changed:\n%s", conf.Bytes())
+	}
+
+	if len(lock.Bytes()) != 0 {
+		t.Errorf("got lock written:\n%s", lock.Bytes())
+	}
+
+	_, err = directory.Stat("go.mk")
+	if err == nil {
+		t.Error("got snippet written")
+	}
+
+	err = mk.InstallContext(ctx, false, maker.StrategyTheirs)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+
+	// the canceled changes are not kept to be written later
+	err = mk.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(conf.Bytes(), []byte("go:")) {
+		t.Errorf("got the canceled snippet written to the configuration:\n%s", conf.Bytes())
+	}
+
+	if bytes.Contains(lock.Bytes(), []byte("go:")) {
+		t.Errorf("got the canceled snippet written to the lock:\n%s", lock.Bytes())
+	}
+
+	_, err = directory.Stat("go.mk")
+	if err == nil {
+		t.Error("got the canceled snippet file written")
+	}
+
+	err = mk.Add("test:go@1.0.0")
+	if err != nil {
+		t.Errorf("got error %v adding the canceled snippet again", err)
+	}
+}
@@ -0,0 +1,125 @@
+package maker
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// MemoryFile is a File kept in memory, to use Maker without touching the disk
+type MemoryFile struct {
+	mutex  sync.Mutex
+	data   []byte
+	offset int64
+}
+
+// NewMemoryFile returns a memory file with a copy of the data as contents
+func NewMemoryFile(data []byte) *MemoryFile {
+	return &MemoryFile{
+		data: append([]byte{}, data...),
+	}
+}
+
+// Bytes returns a copy of the current contents
+func (file *MemoryFile) Bytes() []byte {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	return append([]byte{}, file.data...)
+}
+
+// Read implements io.Reader
+func (file *MemoryFile) Read(data []byte) (int, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if file.offset >= int64(len(file.data)) {
+		return 0, io.EOF
+	}
+
+	count := copy(data, file.data[file.offset:])
+	file.offset += int64(count)
+
+	return count, nil
+}
+
+// Write implements io.Writer
+func (file *MemoryFile) Write(data []byte) (int, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	end := file.offset + int64(len(data))
+	if end > int64(len(file.data)) {
+		file.data = append(file.data, make([]byte, end-int64(len(file.data)))...)
+	}
+
+	copy(file.data[file.offset:], data)
+	file.offset = end
+
+	return len(data), nil
+}
+
+// Seek implements io.Seeker
+func (file *MemoryFile) Seek(offset int64, whence int) (int64, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += file.offset
+	case io.SeekEnd:
+		offset += int64(len(file.data))
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	file.offset = offset
+
+	return offset, nil
+}
+
+// Truncate implements Truncable
+func (file *MemoryFile) Truncate(size int64) error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if size < 0 {
+		return errors.New("negative size")
+	}
+
+	if size > int64(len(file.data)) {
+		file.data = append(file.data, make([]byte, size-int64(len(file.data)))...)
+	}
+
+	file.data = file.data[:size]
+
+	return nil
+}
+
+// Replace implements Replaceable
+func (file *MemoryFile) Replace(data []byte) error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	file.data = append([]byte{}, data...)
+	file.offset = 0
+
+	return nil
+}
+
+// Lock implements Lockable. Memory files are private to the process, so it
+// does nothing
+func (file *MemoryFile) Lock() error {
+	return nil
+}
+
+// Unlock implements Unlockable. Memory files are private to the process, so
+// it does nothing
+func (file *MemoryFile) Unlock() error {
+	return nil
+}
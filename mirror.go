@@ -0,0 +1,41 @@
+package maker
+
+import "strings"
+
+// Mirror rewrites repository URLs before they are fetched, like the git
+// url.<base>.insteadOf setting. Lock entries keep the canonical URLs, so locks
+// are the same with or without mirrors
+type Mirror struct {
+	// URL replaces the matched prefix
+	URL string `yaml:"url"`
+	// InsteadOf lists the URL prefixes to replace
+	InsteadOf []string `yaml:"insteadOf"`
+}
+
+// rewriteURL returns the repository URL with the longest matching prefix of
+// the mirrors replaced. Ties are won by the latter mirror lists, and source
+// kind prefixes are kept, so dir+, tar+ and zip+ URLs match by their location
+func rewriteURL(url string, mirrorLists ...[]Mirror) string {
+	kind, rest := splitSourceURL(url)
+
+	var (
+		match       *Mirror
+		matchLength int
+	)
+
+	for _, mirrors := range mirrorLists {
+		for index := range mirrors {
+			for _, prefix := range mirrors[index].InsteadOf {
+				if prefix != "" && strings.HasPrefix(rest, prefix) && len(prefix) >= matchLength {
+					match, matchLength = &mirrors[index], len(prefix)
+				}
+			}
+		}
+	}
+
+	if match == nil {
+		return url
+	}
+
+	return joinSourceURL(kind, match.URL+rest[matchLength:])
+}
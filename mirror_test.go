@@ -0,0 +1,76 @@
+package maker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/wwmoraes/maker"
+)
+
+func TestMirrors(t *testing.T) {
+	config := `repositories:
+- alias: github
+  url: https://github.com/wwmoraes/snippets.git
+  snippets: {}
+- alias: archives
+  url: tar+https://github.com/wwmoraes/releases
+  snippets: {}
+- alias: gitlab
+  url: https://gitlab.com/wwmoraes/snippets.git
+  snippets: {}
+mirrors:
+- url: https://mirror.example.com/
+  insteadOf:
+  - https://github.com/
+- url: https://mirror.example.com/gitlab/
+  insteadOf:
+  - https://gitlab.com/
+`
+
+	userConfig := &maker.UserConfig{
+		Mirrors: []maker.Mirror{
+			{URL: "https://corp.example.com/github/", InsteadOf: []string{"https://github.com/"}},
+			{URL: "https://corp.example.com/wwmoraes/", InsteadOf: []string{"https://github.com/wwmoraes/releases"}},
+		},
+	}
+
+	fetched := make([]string, 0)
+	repository := snippetsRepository(t, map[string]string{"1.0.0": "one\n"})
+	factory := func(options maker.FetchOptions) (maker.Source, error) {
+		fetched = append(fetched, options.URL)
+		return repository(options)
+	}
+
+	conf := maker.NewMemoryFile([]byte(config))
+	lock := maker.NewMemoryFile(nil)
+
+	mk, err := maker.New(conf, lock, memfs.New(), maker.WithRepositoryFactory(factory), maker.WithUserConfig(userConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("github:go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mk.Outdated()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"https://corp.example.com/github/wwmoraes/snippets.git",
+		"tar+https://corp.example.com/wwmoraes/",
+		"https://mirror.example.com/gitlab/wwmoraes/snippets.git",
+	}
+
+	if strings.Join(fetched, " ") != strings.Join(want, " ") {
+		t.Errorf("got fetched URLs %v, want %v", fetched, want)
+	}
+
+	if !strings.Contains(string(lock.Bytes()), "https://github.com/wwmoraes/snippets.git:") {
+		t.Errorf("lock is not keyed by the canonical URL:\n%s", lock.Bytes())
+	}
+}
@@ -0,0 +1,133 @@
+package maker
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// Option configures an optional behavior of a Maker instance
+type Option func(*options)
+
+type options struct {
+	logger            *slog.Logger
+	subscribers       []Subscriber
+	lockTimeout       time.Duration
+	root              string
+	filesystem        billy.Filesystem
+	snippetsDirectory string
+	configFilename    string
+	lockFilename      string
+	repositoryFactory RepositoryFactory
+	httpClient        *http.Client
+	project           string
+	sharedLock        *sharedLock
+	offline           bool
+	userConfig        *UserConfig
+	cacheDirectory    string
+	jobs              int
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		lockTimeout:       DefaultLockTimeout,
+		snippetsDirectory: SnippetsDirectory,
+		configFilename:    ConfFilename,
+		lockFilename:      LockFilename,
+		repositoryFactory: OpenSource,
+		userConfig:        &UserConfig{},
+		jobs:              1,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithLogger sets the logger used for diagnostics, which are discarded by
+// default
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithLockTimeout sets how long to wait for other maker processes to release
+// the project lock
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.lockTimeout = timeout
+	}
+}
+
+// WithRoot sets the project root directory, which defaults to the current one.
+// It is relative to the filesystem root if one is set
+func WithRoot(dir string) Option {
+	return func(o *options) {
+		o.root = dir
+	}
+}
+
+// WithFilesystem sets the filesystem the project root is on, such as memfs,
+// instead of the OS one. Other processes cannot access such filesystems, so no
+// project lock is held
+func WithFilesystem(filesystem billy.Filesystem) Option {
+	return func(o *options) {
+		o.filesystem = filesystem
+	}
+}
+
+// WithSnippetsDirectory sets the name of the directory the snippets are
+// installed on, relative to the project root
+func WithSnippetsDirectory(name string) Option {
+	return func(o *options) {
+		o.snippetsDirectory = name
+	}
+}
+
+// WithConfigFilename sets the name of the configuration file, relative to the
+// project root
+func WithConfigFilename(name string) Option {
+	return func(o *options) {
+		o.configFilename = name
+	}
+}
+
+// WithLockFilename sets the name of the lock file, relative to the project root
+func WithLockFilename(name string) Option {
+	return func(o *options) {
+		o.lockFilename = name
+	}
+}
+
+// WithRepositoryFactory sets how snippet repositories are fetched, which
+// defaults to cloning them into memory
+func WithRepositoryFactory(factory RepositoryFactory) Option {
+	return func(o *options) {
+		o.repositoryFactory = factory
+	}
+}
+
+// WithOffline makes remote repositories resolve exclusively from the vendor
+// directory written by Maker.Vendor, failing instead of fetching them. Local
+// repositories are still opened in place
+func WithOffline(offline bool) Option {
+	return func(o *options) {
+		o.offline = offline
+	}
+}
+
+// WithHTTPClient sets the client used to fetch repositories over HTTP(S) by
+// this instance only, so instances can use different clients at the same time.
+// Git repositories use it only after UseGitHTTPClients is called
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
@@ -0,0 +1,103 @@
+package maker
+
+import (
+	"context"
+	"sort"
+)
+
+// Outdated describes a snippet whose locked revision differs from the one its
+// pin currently resolves to, or from the latest version available
+type Outdated struct {
+	Repository string
+	Snippet    string
+	Pin        string
+	// Current is the locked version, or commit if unversioned. It is empty if
+	// the snippet is not locked yet
+	Current string
+	// Wanted is the version, or commit if unversioned, the pin resolves to
+	Wanted string
+	// Latest is the highest version available regardless of the pin, for
+	// version pins only
+	Latest string
+}
+
+// Outdated resolves all snippet pins without installing them, and returns the
+// snippets that would change on update or have newer versions out of their
+// pin range, sorted by name
+func (mk *Maker) Outdated() ([]Outdated, error) {
+	return mk.OutdatedContext(context.Background())
+}
+
+// OutdatedContext is like Outdated, stopping if the context is done
+func (mk *Maker) OutdatedContext(ctx context.Context) ([]Outdated, error) {
+	outdated := make([]Outdated, 0)
+
+	err := mk.initRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repository := range mk.conf.Repositories {
+		scheme, err := repository.VersionScheme()
+		if err != nil {
+			return nil, err
+		}
+
+		anyVersion, err := scheme.ParseConstraint("*")
+		if err != nil {
+			return nil, err
+		}
+
+		for name, pin := range repository.Snippets {
+			wanted, err := mk.resolvePin(repository, name, pin)
+			if err != nil {
+				return nil, err
+			}
+
+			item := Outdated{
+				Repository: repository.URL,
+				Snippet:    name,
+				Pin:        pin.String(),
+				Wanted:     entryRevision(wanted),
+			}
+
+			current := mk.lock.Get(repository.URL, name)
+			if current != nil {
+				item.Current = entryRevision(current)
+			}
+
+			if pin.Kind == PinVersion {
+				latest, err := mk.resolveConstraint(repository, name, anyVersion, &LockEntry{})
+				if err == nil {
+					item.Latest = latest.Version
+				}
+			}
+
+			if item.Current == item.Wanted && (item.Latest == "" || item.Latest == item.Current) {
+				continue
+			}
+
+			outdated = append(outdated, item)
+		}
+	}
+
+	sort.Slice(outdated, func(i, j int) bool {
+		return outdated[i].Snippet < outdated[j].Snippet
+	})
+
+	return outdated, nil
+}
+
+// entryRevision returns the entry version, or its abbreviated commit hash if
+// it is not versioned
+func entryRevision(entry *LockEntry) string {
+	if entry.Version != "" {
+		return entry.Version
+	}
+
+	if len(entry.Commit) > 12 {
+		return entry.Commit[:12]
+	}
+
+	return entry.Commit
+}
@@ -0,0 +1,128 @@
+package maker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+func TestPatch(t *testing.T) {
+	url := localSnippetsRepository(t, map[string]string{
+		"1.0.0": "one\ntwo\nthree\nfour\nfive\n",
+		"1.1.0": "one\ntwo\nthree\nfour\nfive\nsix\n",
+		"2.0.0": "uno\ntwo\nthree\nfour\nfive\nsix\n",
+	})
+
+	project := memfs.New()
+
+	err := util.WriteFile(project, maker.ConfFilename, pinConfig(url, "=1.0.0"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mk := newProjectMaker(t, project)
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directory, err := project.Chroot(maker.SnippetsDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = util.WriteFile(directory, "go.mk", []byte("ONE\ntwo\nthree\nfour\nfive\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Patch("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = directory.Stat("patches/go.patch")
+	if err != nil {
+		t.Fatalf("got no patch file: %v", err)
+	}
+
+	lockData, err := util.ReadFile(project, maker.LockFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(lockData), "patched: ") {
+		t.Errorf("lock does not record the patched hash:\n%s", lockData)
+	}
+
+	// installing again applies the patch on the upstream contents
+	err = directory.Remove("go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Install(false, maker.StrategyFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertSnippet(t, directory, "ONE\ntwo\nthree\nfour\nfive\n")
+
+	err = util.WriteFile(project, maker.ConfFilename, pinConfig(url, "^1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newProjectMaker(t, project).Update(maker.StrategyFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertSnippet(t, directory, "ONE\ntwo\nthree\nfour\nfive\nsix\n")
+
+	// upstream changed the patched line
+	lockData, err = util.ReadFile(project, maker.LockFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = util.WriteFile(project, maker.ConfFilename, pinConfig(url, "*"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newProjectMaker(t, project).Update(maker.StrategyFail)
+	if err == nil || !strings.Contains(err.Error(), "go.patch no longer applies to snippet go") {
+		t.Errorf("got error %v, want the patch to no longer apply", err)
+	}
+
+	data, err := util.ReadFile(project, maker.LockFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != string(lockData) {
+		t.Errorf("got lock written despite the error:\n%s", data)
+	}
+
+	assertSnippet(t, directory, "ONE\ntwo\nthree\nfour\nfive\nsix\n")
+}
+
+// assertSnippet fails the test if the go snippet does not have the contents
+func assertSnippet(t *testing.T, directory billy.Filesystem, want string) {
+	t.Helper()
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != want {
+		t.Errorf("got snippet contents %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,144 @@
+package maker
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/wwmoraes/maker/pkg/semver"
+)
+
+// PinKind identifies how a snippet reference is resolved into a revision
+type PinKind string
+
+const (
+	// PinVersion resolves to the highest tag that satisfies a version constraint
+	PinVersion PinKind = "version"
+	// PinBranch resolves to the current head of a branch
+	PinBranch PinKind = "branch"
+	// PinTag resolves to the revision of a tag
+	PinTag PinKind = "tag"
+	// PinCommit resolves to a fixed commit, and is never updated
+	PinCommit PinKind = "commit"
+)
+
+// Pin stores the reference a snippet is required at, which is either a version
+// constraint or an explicit branch, tag or commit, e.g. ^1.2, branch:main,
+// tag:foo or commit:abc123
+type Pin struct {
+	Kind       PinKind
+	Reference  string
+	Constraint semver.C
+	// text is the constraint as written on schemes other than semantic
+	// versioning, as their versions are normalized when parsed, e.g. Go module
+	// versions lose their v prefix
+	text string
+	// bare is set on branch pins written without their kind, as older
+	// releases did, which are deprecated
+	bare bool
+}
+
+// ParsePin returns a pin from its string representation, using the version
+// scheme to parse constraints. References other than constraints need their
+// kind, e.g. branch:main
+func ParsePin(pinStr string, scheme semver.VersionScheme) (Pin, error) {
+	kind, reference, found := strings.Cut(pinStr, ":")
+	if !found {
+		constraint, err := scheme.ParseConstraint(pinStr)
+		if err != nil {
+			return Pin{}, err
+		}
+
+		pin := Pin{
+			Kind:       PinVersion,
+			Constraint: semver.C{Constraint: constraint},
+		}
+
+		if scheme.Name() != semver.SchemeSemVer {
+			pin.text = pinStr
+		}
+
+		return pin, nil
+	}
+
+	if reference == "" {
+		return Pin{}, fmt.Errorf("empty %s reference", kind)
+	}
+
+	switch PinKind(kind) {
+	case PinBranch, PinTag, PinCommit:
+		return Pin{
+			Kind:      PinKind(kind),
+			Reference: reference,
+		}, nil
+	default:
+		return Pin{}, fmt.Errorf("unknown pin kind %s", kind)
+	}
+}
+
+// parseConfigPin parses a pin as written on a configuration file, where bare
+// branch names, e.g. master, are still accepted as branch pins, as older
+// releases wrote them, and written with their kind from then on
+func parseConfigPin(pinStr string, scheme semver.VersionScheme) (Pin, error) {
+	pin, err := ParsePin(pinStr, scheme)
+	if err != nil && !strings.Contains(pinStr, ":") && isBareBranch(pinStr) {
+		return Pin{Kind: PinBranch, Reference: pinStr, bare: true}, nil
+	}
+
+	return pin, err
+}
+
+// isBareBranch returns true if the pin looks like a branch name instead of a
+// version constraint, e.g. master
+func isBareBranch(pinStr string) bool {
+	if pinStr == "" || strings.ContainsAny(pinStr, " ^~<>=*|,") {
+		return false
+	}
+
+	first := rune(pinStr[0])
+	if !unicode.IsLetter(first) {
+		return false
+	}
+
+	// versions are often prefixed with v, e.g. v1.2
+	return !(first == 'v' && len(pinStr) > 1 && unicode.IsDigit(rune(pinStr[1])))
+}
+
+// String returns the pin representation as used on the configuration file
+func (pin Pin) String() string {
+	if pin.Kind == PinVersion && pin.text != "" {
+		return pin.text
+	}
+
+	if pin.Kind == PinVersion {
+		return pin.Constraint.String()
+	}
+
+	return fmt.Sprintf("%s:%s", pin.Kind, pin.Reference)
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (pin Pin) MarshalYAML() (interface{}, error) {
+	return pin.String(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler using the default version scheme,
+// and accepts bare branch names as configuration files do. Repositories parse
+// their pins with their own scheme instead
+func (pin *Pin) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var pinStr string
+
+	err := unmarshal(&pinStr)
+	if err != nil {
+		return err
+	}
+
+	scheme, err := semver.NewScheme("")
+	if err != nil {
+		return err
+	}
+
+	*pin, err = parseConfigPin(pinStr, scheme)
+
+	return err
+}
@@ -0,0 +1,203 @@
+package maker_test
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+	"github.com/wwmoraes/maker/pkg/semver"
+)
+
+func TestParsePin(t *testing.T) {
+	scheme, err := semver.NewScheme("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pin       string
+		kind      maker.PinKind
+		reference string
+		want      string
+	}{
+		{pin: "^1.2.0", kind: maker.PinVersion, want: "^1.2.0"},
+		{pin: "*", kind: maker.PinVersion, want: "*"},
+		{pin: "branch:main", kind: maker.PinBranch, reference: "main", want: "branch:main"},
+		{pin: "tag:foo", kind: maker.PinTag, reference: "foo", want: "tag:foo"},
+		{pin: "commit:abc123", kind: maker.PinCommit, reference: "abc123", want: "commit:abc123"},
+	}
+
+	for _, test := range tests {
+		pin, err := maker.ParsePin(test.pin, scheme)
+		if err != nil {
+			t.Errorf("%s: %v", test.pin, err)
+			continue
+		}
+
+		if pin.Kind != test.kind || pin.Reference != test.reference {
+			t.Errorf("%s: got pin %s %q, want %s %q", test.pin, pin.Kind, pin.Reference, test.kind, test.reference)
+		}
+
+		if pin.String() != test.want {
+			t.Errorf("%s: got string %q, want %q", test.pin, pin.String(), test.want)
+		}
+
+		again, err := maker.ParsePin(pin.String(), scheme)
+		if err != nil || again.String() != pin.String() {
+			t.Errorf("%s: got %q, %v parsing %q again", test.pin, again.String(), err, pin.String())
+		}
+	}
+
+	for _, pinStr := range []string{"branch:", "foo:bar", "1.2.3.4", "v1.x.y", "^1 ||", "master", "latest"} {
+		_, err := maker.ParsePin(pinStr, scheme)
+		if err == nil {
+			t.Errorf("%s: got no error", pinStr)
+		}
+	}
+}
+
+func TestBareBranchPin(t *testing.T) {
+	dir := t.TempDir()
+	newUpstreamRepository(t, dir)("one\n", "", false)
+
+	var logs bytes.Buffer
+
+	conf := maker.NewMemoryFile([]byte("repositories:\n- url: " + dir + "\n  snippets:\n    go: master\n"))
+
+	mk, err := maker.New(conf, maker.NewMemoryFile(nil), memfs.New(), maker.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(logs.String(), "deprecated") || !strings.Contains(logs.String(), "snippet=go") {
+		t.Errorf("got no deprecation warning for the go snippet:\n%s", logs.String())
+	}
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(conf.Bytes()), "go: branch:master") {
+		t.Errorf("got configuration without the migrated pin:\n%s", conf.Bytes())
+	}
+}
+
+func TestAddBarePin(t *testing.T) {
+	dir := t.TempDir()
+	newUpstreamRepository(t, dir)("one\n", "", false)
+
+	config := "repositories:\n- url: " + filepath.ToSlash(dir) + "\n"
+	conf := maker.NewMemoryFile([]byte(config))
+	directory := memfs.New()
+
+	mk, err := maker.New(conf, maker.NewMemoryFile(nil), directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only configuration files written by older releases take bare branches
+	err = mk.Add("go@master")
+	if err == nil {
+		t.Error("got no error adding a snippet pinned to a bare branch name")
+	}
+
+	if string(conf.Bytes()) != config {
+		t.Errorf("got configuration changed by a failed add:\n%s", conf.Bytes())
+	}
+
+	err = mk.Add("go@branch:master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(conf.Bytes()), "go: branch:master") {
+		t.Errorf("got configuration without the branch pin:\n%s", conf.Bytes())
+	}
+
+	assertSnippet(t, directory, "one\n")
+}
+
+// updateSnippet updates the snippets of a configuration installed on the
+// directory, returning the new lock contents
+func updateSnippet(t *testing.T, config, lock []byte, directory billy.Filesystem) []byte {
+	t.Helper()
+
+	lockFile := maker.NewMemoryFile(lock)
+
+	mk, err := maker.New(maker.NewMemoryFile(config), lockFile, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lock == nil {
+		err = mk.Install(false, maker.StrategyTheirs)
+	} else {
+		err = mk.Update(maker.StrategyTheirs)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lockFile.Bytes()
+}
+
+func TestUpdatePins(t *testing.T) {
+	dir := t.TempDir()
+	commit := newUpstreamRepository(t, dir)
+
+	first := commit("one\n", "", false)
+
+	// branches follow their head
+	config := []byte("repositories:\n- url: " + filepath.ToSlash(dir) + "\n  snippets:\n    go: branch:master\n")
+	directory := memfs.New()
+
+	lock := updateSnippet(t, config, nil, directory)
+	if !strings.Contains(string(lock), "commit: "+first.String()) {
+		t.Errorf("got lock not at the first commit:\n%s", lock)
+	}
+
+	second := commit("two\n", "", false)
+
+	lock = updateSnippet(t, config, lock, directory)
+	if !strings.Contains(string(lock), "commit: "+second.String()) || !strings.Contains(string(lock), "tag: master") {
+		t.Errorf("got lock not at the branch head:\n%s", lock)
+	}
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "two\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "two\n")
+	}
+
+	// commits are never moved
+	config = []byte("repositories:\n- url: " + filepath.ToSlash(dir) + "\n  snippets:\n    go: commit:" + first.String() + "\n")
+	directory = memfs.New()
+
+	lock = updateSnippet(t, config, nil, directory)
+	commit("three\n", "", false)
+
+	lock = updateSnippet(t, config, lock, directory)
+	if !strings.Contains(string(lock), "commit: "+first.String()) {
+		t.Errorf("got lock moved from the pinned commit:\n%s", lock)
+	}
+
+	data, err = util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "one\n")
+	}
+}
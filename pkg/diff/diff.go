@@ -0,0 +1,112 @@
+// Package diff provides line-based comparison and merging of text contents,
+// such as snippet files.
+package diff
+
+import (
+	"bytes"
+)
+
+// Hunk represents a changed region between two texts, as half-open line ranges
+// on both the source (A) and target (B) sides. An empty range on A means an
+// insertion, and an empty range on B means a deletion
+type Hunk struct {
+	AStart, AEnd int
+	BStart, BEnd int
+}
+
+// Lines splits the data into lines, keeping their line terminators
+func Lines(data []byte) []string {
+	if len(data) == 0 {
+		return []string{}
+	}
+
+	parts := bytes.SplitAfter(data, []byte("\n"))
+	lines := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+
+		lines = append(lines, string(part))
+	}
+
+	return lines
+}
+
+// Diff returns the hunks that transform the a lines into the b ones, based on
+// their longest common subsequence
+func Diff(a, b []string) []Hunk {
+	// skip the common prefix and suffix, as they're usually most of the content
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	middleA := a[prefix : len(a)-suffix]
+	middleB := b[prefix : len(b)-suffix]
+
+	// lengths[i][j] holds the LCS length of middleA[i:] and middleB[j:]
+	lengths := make([][]int, len(middleA)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(middleB)+1)
+	}
+
+	for i := len(middleA) - 1; i >= 0; i-- {
+		for j := len(middleB) - 1; j >= 0; j-- {
+			if middleA[i] == middleB[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	hunks := make([]Hunk, 0)
+	current := Hunk{-1, -1, -1, -1}
+
+	flush := func(i, j int) {
+		if current.AStart == -1 {
+			return
+		}
+
+		current.AEnd, current.BEnd = prefix+i, prefix+j
+		hunks = append(hunks, current)
+		current = Hunk{-1, -1, -1, -1}
+	}
+
+	open := func(i, j int) {
+		if current.AStart != -1 {
+			return
+		}
+
+		current.AStart, current.BStart = prefix+i, prefix+j
+	}
+
+	i, j := 0, 0
+	for i < len(middleA) || j < len(middleB) {
+		switch {
+		case i < len(middleA) && j < len(middleB) && middleA[i] == middleB[j]:
+			flush(i, j)
+			i++
+			j++
+		case j >= len(middleB) || (i < len(middleA) && lengths[i+1][j] >= lengths[i][j+1]):
+			open(i, j)
+			i++
+		default:
+			open(i, j)
+			j++
+		}
+	}
+
+	flush(i, j)
+
+	return hunks
+}
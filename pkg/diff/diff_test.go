@@ -0,0 +1,55 @@
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wwmoraes/maker/pkg/diff"
+)
+
+func TestLines(t *testing.T) {
+	testCases := map[string][]string{
+		"":           {},
+		"a":          {"a"},
+		"a\n":        {"a\n"},
+		"a\nb":       {"a\n", "b"},
+		"a\n\nb\n":   {"a\n", "\n", "b\n"},
+		"a\r\nb\r\n": {"a\r\n", "b\r\n"},
+	}
+
+	for data, want := range testCases {
+		got := diff.Lines([]byte(data))
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected [%q], got [%q]", want, got)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		name string
+		a, b string
+		want []diff.Hunk
+	}{
+		{"equal", "a\nb\nc\n", "a\nb\nc\n", []diff.Hunk{}},
+		{"insert", "a\nc\n", "a\nb\nc\n", []diff.Hunk{{1, 1, 1, 2}}},
+		{"delete", "a\nb\nc\n", "a\nc\n", []diff.Hunk{{1, 2, 1, 1}}},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n", []diff.Hunk{{1, 2, 1, 2}}},
+		{"append", "a\n", "a\nb\n", []diff.Hunk{{1, 1, 1, 2}}},
+		{"empty source", "", "a\nb\n", []diff.Hunk{{0, 0, 0, 2}}},
+		{"empty target", "a\nb\n", "", []diff.Hunk{{0, 2, 0, 0}}},
+		{"multiple", "a\nb\nc\nd\ne\n", "x\nb\nc\ne\ny\n", []diff.Hunk{{0, 1, 0, 1}, {3, 4, 3, 3}, {5, 5, 4, 5}}},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := diff.Diff(diff.Lines([]byte(tt.a)), diff.Lines([]byte(tt.b)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected [%++v], got [%++v]", tt.want, got)
+			}
+		})
+	}
+}
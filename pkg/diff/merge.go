@@ -0,0 +1,154 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+)
+
+// Labels names the sides of a three-way merge on conflict markers
+type Labels struct {
+	Ours, Base, Theirs string
+}
+
+// sideHunk is a hunk against the merge base, tagged with the side it came from
+type sideHunk struct {
+	Hunk
+	side int
+}
+
+const (
+	sideOurs = iota
+	sideTheirs
+)
+
+// Merge performs a three-way merge of the changes made from the base contents
+// on both ours and theirs. Changes from a single side are applied directly,
+// while overlapping changes that differ are written with diff3-style conflict
+// markers. It returns the merged contents and true if there's any conflict
+func Merge(base, ours, theirs []byte, labels Labels) ([]byte, bool) {
+	baseLines, oursLines, theirsLines := Lines(base), Lines(ours), Lines(theirs)
+	sides := [][]string{oursLines, theirsLines}
+
+	hunks := make([]sideHunk, 0)
+	for _, hunk := range Diff(baseLines, oursLines) {
+		hunks = append(hunks, sideHunk{hunk, sideOurs})
+	}
+
+	for _, hunk := range Diff(baseLines, theirsLines) {
+		hunks = append(hunks, sideHunk{hunk, sideTheirs})
+	}
+
+	sort.SliceStable(hunks, func(i, j int) bool {
+		return hunks[i].AStart < hunks[j].AStart
+	})
+
+	var result strings.Builder
+	conflict := false
+	basePos := 0
+
+	for i := 0; i < len(hunks); {
+		// group all hunks that overlap or touch each other on the base
+		lo, hi := hunks[i].AStart, hunks[i].AEnd
+		j := i + 1
+		for j < len(hunks) && hunks[j].AStart <= hi {
+			if hunks[j].AEnd > hi {
+				hi = hunks[j].AEnd
+			}
+			j++
+		}
+
+		group := hunks[i:j]
+		i = j
+
+		writeLines(&result, baseLines[basePos:lo])
+		basePos = hi
+
+		// compute each side contents for the base region of the group
+		regions := [][]string{baseLines[lo:hi], baseLines[lo:hi]}
+		changed := []bool{false, false}
+
+		for side := range sides {
+			var first, last *sideHunk
+			for index := range group {
+				if group[index].side != side {
+					continue
+				}
+
+				if first == nil {
+					first = &group[index]
+				}
+
+				last = &group[index]
+			}
+
+			if first == nil {
+				continue
+			}
+
+			start := first.BStart - (first.AStart - lo)
+			end := last.BEnd + (hi - last.AEnd)
+			regions[side] = sides[side][start:end]
+			changed[side] = true
+		}
+
+		switch {
+		case !changed[sideTheirs]:
+			writeLines(&result, regions[sideOurs])
+		case !changed[sideOurs]:
+			writeLines(&result, regions[sideTheirs])
+		case equalLines(regions[sideOurs], regions[sideTheirs]):
+			writeLines(&result, regions[sideOurs])
+		default:
+			conflict = true
+			writeMarker(&result, "<<<<<<<", labels.Ours)
+			writeLines(&result, regions[sideOurs])
+			writeMarker(&result, "|||||||", labels.Base)
+			writeLines(&result, baseLines[lo:hi])
+			writeMarker(&result, "=======", "")
+			writeLines(&result, regions[sideTheirs])
+			writeMarker(&result, ">>>>>>>", labels.Theirs)
+		}
+	}
+
+	writeLines(&result, baseLines[basePos:])
+
+	return []byte(result.String()), conflict
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeLines(builder *strings.Builder, lines []string) {
+	for _, line := range lines {
+		builder.WriteString(line)
+	}
+}
+
+// writeMarker writes a conflict marker line, terminating any previous
+// unterminated line first
+func writeMarker(builder *strings.Builder, marker, label string) {
+	current := builder.String()
+	if len(current) > 0 && !strings.HasSuffix(current, "\n") {
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString(marker)
+
+	if label != "" {
+		builder.WriteString(" ")
+		builder.WriteString(label)
+	}
+
+	builder.WriteString("\n")
+}
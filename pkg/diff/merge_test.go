@@ -0,0 +1,86 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/wwmoraes/maker/pkg/diff"
+)
+
+func TestMerge(t *testing.T) {
+	labels := diff.Labels{Ours: "local", Base: "base", Theirs: "upstream"}
+
+	testCases := []struct {
+		name             string
+		base, ours, want string
+		theirs           string
+		wantConflict     bool
+	}{
+		{
+			name:   "unchanged",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "ours only",
+			base:   "a\nb\nc\n",
+			ours:   "a\nB\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:   "theirs only",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\nd\n",
+			want:   "a\nb\nc\nd\n",
+		},
+		{
+			name:   "both apart",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "both equal",
+			base:   "a\nb\nc\n",
+			ours:   "a\nx\nc\n",
+			theirs: "a\nx\nc\n",
+			want:   "a\nx\nc\n",
+		},
+		{
+			name:         "conflict",
+			base:         "a\nb\nc\n",
+			ours:         "a\nx\nc\n",
+			theirs:       "a\ny\nc\n",
+			want:         "a\n<<<<<<< local\nx\n||||||| base\nb\n=======\ny\n>>>>>>> upstream\nc\n",
+			wantConflict: true,
+		},
+		{
+			name:         "conflict without trailing newline",
+			base:         "a\nb",
+			ours:         "a\nx",
+			theirs:       "a\ny",
+			want:         "a\n<<<<<<< local\nx\n||||||| base\nb\n=======\ny\n>>>>>>> upstream\n",
+			wantConflict: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, gotConflict := diff.Merge([]byte(tt.base), []byte(tt.ours), []byte(tt.theirs), labels)
+			if string(got) != tt.want {
+				t.Fatalf("expected [%q], got [%q]", tt.want, string(got))
+			}
+
+			if gotConflict != tt.wantConflict {
+				t.Fatalf("expected conflict %v, got %v", tt.wantConflict, gotConflict)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultContext is the amount of unchanged lines around each change written
+// on unified diffs
+const DefaultContext = 3
+
+const noNewlineMarker = "\\ No newline at end of file"
+
+var (
+	// ErrInvalidPatch means a patch that is not on the unified diff format
+	ErrInvalidPatch = errors.New("invalid unified diff")
+	// ErrHunkFailed means a patch hunk whose lines were not found on the target
+	ErrHunkFailed = errors.New("hunk does not apply")
+)
+
+var hunkHeaderRule = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Unified returns the unified diff that transforms the a contents into the b
+// ones, with the given amount of context lines. It returns nil if both
+// contents are equal
+func Unified(a, b []byte, nameA, nameB string, context int) []byte {
+	aLines, bLines := Lines(a), Lines(b)
+	hunks := Diff(aLines, bLines)
+
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+
+	fmt.Fprintf(&buffer, "--- %s\n", nameA)
+	fmt.Fprintf(&buffer, "+++ %s\n", nameB)
+
+	for i := 0; i < len(hunks); {
+		// group hunks whose contexts overlap
+		j := i + 1
+		for j < len(hunks) && hunks[j].AStart-hunks[j-1].AEnd <= 2*context {
+			j++
+		}
+
+		first, last := hunks[i], hunks[j-1]
+		aStart := first.AStart - context
+		if aStart < 0 {
+			aStart = 0
+		}
+
+		aEnd := last.AEnd + context
+		if aEnd > len(aLines) {
+			aEnd = len(aLines)
+		}
+
+		bStart := first.BStart - (first.AStart - aStart)
+		bEnd := last.BEnd + (aEnd - last.AEnd)
+
+		fmt.Fprintf(&buffer, "@@ -%s +%s @@\n", hunkRange(aStart, aEnd), hunkRange(bStart, bEnd))
+
+		position := aStart
+		for _, hunk := range hunks[i:j] {
+			writePatchLines(&buffer, " ", aLines[position:hunk.AStart])
+			writePatchLines(&buffer, "-", aLines[hunk.AStart:hunk.AEnd])
+			writePatchLines(&buffer, "+", bLines[hunk.BStart:hunk.BEnd])
+			position = hunk.AEnd
+		}
+
+		writePatchLines(&buffer, " ", aLines[position:aEnd])
+
+		i = j
+	}
+
+	return buffer.Bytes()
+}
+
+// hunkRange formats a half-open line range as an unified diff range, which is
+// one-based and refers to the preceding line when empty
+func hunkRange(start, end int) string {
+	length := end - start
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if length == 1 {
+		return strconv.Itoa(start + 1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func writePatchLines(buffer *bytes.Buffer, prefix string, lines []string) {
+	for _, line := range lines {
+		buffer.WriteString(prefix)
+		buffer.WriteString(line)
+
+		if !strings.HasSuffix(line, "\n") {
+			buffer.WriteString("\n")
+			buffer.WriteString(noNewlineMarker)
+			buffer.WriteString("\n")
+		}
+	}
+}
+
+// patchHunk holds the lines a hunk expects to find and the ones that replace
+// them, along with the one-based line position it was created at
+type patchHunk struct {
+	position int
+	old, new []string
+}
+
+// parseUnified returns the hunks of a unified diff
+func parseUnified(patch []byte) ([]patchHunk, error) {
+	hunks := make([]patchHunk, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(patch))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(patch)+1)
+
+	var (
+		current            *patchHunk
+		lastSide           byte
+		oldCount, newCount int
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if current != nil && line == noNewlineMarker {
+			switch lastSide {
+			case ' ':
+				current.old[len(current.old)-1] = strings.TrimSuffix(current.old[len(current.old)-1], "\n")
+				current.new[len(current.new)-1] = strings.TrimSuffix(current.new[len(current.new)-1], "\n")
+			case '-':
+				current.old[len(current.old)-1] = strings.TrimSuffix(current.old[len(current.old)-1], "\n")
+			case '+':
+				current.new[len(current.new)-1] = strings.TrimSuffix(current.new[len(current.new)-1], "\n")
+			}
+
+			continue
+		}
+
+		// file headers and any text between hunks
+		if oldCount == 0 && newCount == 0 {
+			matches := hunkHeaderRule.FindStringSubmatch(line)
+			if matches == nil {
+				current = nil
+				continue
+			}
+
+			position, _ := strconv.Atoi(matches[1])
+			oldCount, newCount = hunkLength(matches[2]), hunkLength(matches[4])
+			hunks = append(hunks, patchHunk{position: position})
+			current = &hunks[len(hunks)-1]
+
+			continue
+		}
+
+		if len(line) == 0 {
+			return nil, ErrInvalidPatch
+		}
+
+		lastSide = line[0]
+		content := line[1:] + "\n"
+
+		switch lastSide {
+		case ' ':
+			current.old = append(current.old, content)
+			current.new = append(current.new, content)
+			oldCount--
+			newCount--
+		case '-':
+			current.old = append(current.old, content)
+			oldCount--
+		case '+':
+			current.new = append(current.new, content)
+			newCount--
+		default:
+			return nil, ErrInvalidPatch
+		}
+
+		if oldCount < 0 || newCount < 0 {
+			return nil, ErrInvalidPatch
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(hunks) == 0 || oldCount != 0 || newCount != 0 {
+		return nil, ErrInvalidPatch
+	}
+
+	return hunks, nil
+}
+
+// hunkLength parses an optional hunk range length, which defaults to one
+func hunkLength(lengthStr string) int {
+	if lengthStr == "" {
+		return 1
+	}
+
+	length, _ := strconv.Atoi(lengthStr)
+
+	return length
+}
+
+// Apply returns the data with the unified diff patch applied. Hunks are
+// searched around their original positions, so patches still apply if the
+// lines moved, as long as the changed and context lines are intact
+func Apply(data, patch []byte) ([]byte, error) {
+	hunks, err := parseUnified(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := Lines(data)
+	result := make([]string, 0, len(lines))
+	position := 0
+	offset := 0
+
+	for index, hunk := range hunks {
+		origin := hunk.position - 1
+		if len(hunk.old) == 0 {
+			// pure insertions refer to the line before them
+			origin = hunk.position
+		}
+
+		start := findLines(lines, hunk.old, position, origin+offset)
+		if start == -1 {
+			return nil, fmt.Errorf("hunk #%d at line %d: %w", index+1, hunk.position, ErrHunkFailed)
+		}
+
+		result = append(result, lines[position:start]...)
+		result = append(result, hunk.new...)
+		position = start + len(hunk.old)
+		offset = start - origin
+	}
+
+	result = append(result, lines[position:]...)
+
+	return []byte(strings.Join(result, "")), nil
+}
+
+// findLines returns the index of the needle lines within the haystack ones
+// nearest to the expected index, not before the minimum one, or -1 if they're
+// not found
+func findLines(haystack, needle []string, minimum, expected int) int {
+	if expected < minimum {
+		expected = minimum
+	}
+
+	if expected > len(haystack) {
+		expected = len(haystack)
+	}
+
+	for distance := 0; ; distance++ {
+		before, after := expected-distance, expected+distance
+		if before < minimum && after+len(needle) > len(haystack) {
+			return -1
+		}
+
+		if after+len(needle) <= len(haystack) && equalLines(haystack[after:after+len(needle)], needle) {
+			return after
+		}
+
+		if before >= minimum && before != after && before+len(needle) <= len(haystack) && equalLines(haystack[before:before+len(needle)], needle) {
+			return before
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package diff_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wwmoraes/maker/pkg/diff"
+)
+
+func TestUnified(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+
+	want := "--- a/x.mk\n+++ b/x.mk\n" +
+		"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+		"@@ -8,3 +8,4 @@\n h\n i\n j\n+k\n"
+
+	got := diff.Unified([]byte(a), []byte(b), "a/x.mk", "b/x.mk", diff.DefaultContext)
+	if string(got) != want {
+		t.Fatalf("expected [%q], got [%q]", want, string(got))
+	}
+
+	if patch := diff.Unified([]byte(a), []byte(a), "a/x.mk", "b/x.mk", diff.DefaultContext); patch != nil {
+		t.Fatalf("expected nil, got [%q]", string(patch))
+	}
+}
+
+func TestUnified_NoNewline(t *testing.T) {
+	a := "a\nb"
+	b := "a\nc\n"
+
+	want := "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n"
+
+	got := diff.Unified([]byte(a), []byte(b), "a", "b", diff.DefaultContext)
+	if string(got) != want {
+		t.Fatalf("expected [%q], got [%q]", want, string(got))
+	}
+
+	applied, err := diff.Apply([]byte(a), got)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if string(applied) != b {
+		t.Fatalf("expected [%q], got [%q]", b, string(applied))
+	}
+}
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		name      string
+		a, b      string
+		target    string
+		want      string
+		wantError error
+	}{
+		{
+			name:   "same contents",
+			a:      "a\nb\nc\nd\n",
+			b:      "a\nx\nc\nd\n",
+			target: "a\nb\nc\nd\n",
+			want:   "a\nx\nc\nd\n",
+		},
+		{
+			name:   "moved lines",
+			a:      "a\nb\nc\nd\ne\nf\ng\n",
+			b:      "a\nb\nc\nD\ne\nf\ng\n",
+			target: "0\n1\na\nb\nc\nd\ne\nf\ng\n",
+			want:   "0\n1\na\nb\nc\nD\ne\nf\ng\n",
+		},
+		{
+			name:   "removed dashed line",
+			a:      "a\n-- b\nc\n",
+			b:      "a\nc\n",
+			target: "a\n-- b\nc\n",
+			want:   "a\nc\n",
+		},
+		{
+			name:      "changed lines",
+			a:         "a\nb\nc\nd\n",
+			b:         "a\nx\nc\nd\n",
+			target:    "a\ny\nc\nd\n",
+			wantError: diff.ErrHunkFailed,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			patch := diff.Unified([]byte(tt.a), []byte(tt.b), "a", "b", diff.DefaultContext)
+
+			got, err := diff.Apply([]byte(tt.target), patch)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("expected error [%v], got [%v]", tt.wantError, err)
+			}
+
+			if string(got) != tt.want {
+				t.Fatalf("expected [%q], got [%q]", tt.want, string(got))
+			}
+		})
+	}
+}
+
+func TestInvalidApply(t *testing.T) {
+	patches := []string{
+		"",
+		"lorem ipsum\n",
+		"@@ -1,2 +1,2 @@\n a\n",
+		"@@ -1 +1 @@\n*a\n",
+	}
+
+	for _, patch := range patches {
+		_, err := diff.Apply([]byte("a\n"), []byte(patch))
+		if !errors.Is(err, diff.ErrInvalidPatch) {
+			t.Fatalf("expected error [%v], got [%v]", diff.ErrInvalidPatch, err)
+		}
+	}
+}
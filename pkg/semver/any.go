@@ -24,7 +24,7 @@ func NewAnyWith(version PartialVersion) Constraint {
 }
 
 func (source *any) Match(target Version, includePrerelease bool) bool {
-	return !target.IsPrerelease()
+	return includePrerelease || !target.IsPrerelease()
 }
 
 func (source *any) IsPrerelease() bool {
@@ -63,3 +63,13 @@ func TestNewAny_IsPrerelease(t *testing.T) {
 		)
 	}
 }
+
+func TestNewAny_MatchIncludePrerelease(t *testing.T) {
+	constraint := mustNewSpecificConstraint(t, "*", semver.NewAny)
+
+	for _, versionStr := range []string{"1.0.0", "1.0.0-alpha", "0.0.0-20211018120000-abcdef123456"} {
+		if !constraint.Match(mustNewVersion(t, versionStr), true) {
+			t.Errorf("expected %s to match %s including prereleases", constraint, versionStr)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var calverRule = regexp.MustCompile(`^(?P<year>[1-9]\d{3})\.(?P<month>0?[1-9]|1[0-2])(?:\.(?P<micro>\d+))?(?:-(?P<prerelease>[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?(?:\+(?P<buildmetadata>[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// leadingZeroesRule matches zero-padded numeric identifiers, as used by
+// calendar versions on months (e.g. 2021.01.0)
+var leadingZeroesRule = regexp.MustCompile(`(^|[^0-9a-zA-Z-])0+(\d)`)
+
+// calverScheme implements calendar versioning on the YYYY.MM.MICRO format,
+// where month can be zero-padded and micro is optional (e.g. 2021.10.1 or
+// 2021.01). Versions map to semantic ones as major, minor and patch, so the
+// same constraint syntax is supported
+type calverScheme struct{}
+
+func (scheme *calverScheme) Name() string {
+	return SchemeCalVer
+}
+
+func (scheme *calverScheme) ParseVersion(versionStr string) (Version, error) {
+	matches := calverRule.FindStringSubmatch(versionStr)
+	if matches == nil {
+		return nil, &ParseError{
+			Func:  "calver.ParseVersion",
+			Input: versionStr,
+			Err:   ErrInvalidVersion,
+		}
+	}
+
+	year, _ := strconv.Atoi(matches[1])
+	month, _ := strconv.Atoi(matches[2])
+	micro, _ := strconv.Atoi(matches[3])
+
+	var strBuilder strings.Builder
+
+	fmt.Fprintf(&strBuilder, "%d.%d.%d", year, month, micro)
+
+	if matches[4] != "" {
+		fmt.Fprintf(&strBuilder, "-%s", matches[4])
+	}
+
+	if matches[5] != "" {
+		fmt.Fprintf(&strBuilder, "+%s", matches[5])
+	}
+
+	version, err := NewVersion(strBuilder.String())
+	if err != nil {
+		return nil, &ParseError{
+			Func:  "calver.ParseVersion",
+			Input: versionStr,
+			Err:   err,
+		}
+	}
+
+	return version, nil
+}
+
+func (scheme *calverScheme) ParseConstraint(constraintStr string) (Constraint, error) {
+	constraint, err := NewConstraint(leadingZeroesRule.ReplaceAllString(constraintStr, "$1$2"))
+	if err != nil {
+		return nil, &ParseError{
+			Func:  "calver.ParseConstraint",
+			Input: constraintStr,
+			Err:   err,
+		}
+	}
+
+	return constraint, nil
+}
+
+func (scheme *calverScheme) Compare(source, target Version) int {
+	return source.Compare(target)
+}
+
+func (scheme *calverScheme) Format(version Version) string {
+	versionStr := fmt.Sprintf("%d.%02d.%d", version.Major(), version.Minor(), version.Patch())
+
+	if version.IsPrerelease() {
+		versionStr = fmt.Sprintf("%s-%s", versionStr, version.Prerelease())
+	}
+
+	if version.IsBuild() {
+		versionStr = fmt.Sprintf("%s+%s", versionStr, version.Build())
+	}
+
+	return versionStr
+}
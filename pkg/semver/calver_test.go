@@ -0,0 +1,87 @@
+package semver_test
+
+import (
+	"testing"
+
+	"github.com/wwmoraes/maker/pkg/semver"
+)
+
+func TestCalVerScheme(t *testing.T) {
+	scheme := mustNewScheme(t, semver.SchemeCalVer)
+
+	testCases := []schemeScenario{
+		{"*", "2021.10.1", true},
+		{">=2021.01", "2021.10.1", true},
+		{">=2021.01", "2020.12.3", false},
+		{"~2021.10", "2021.10", true},
+		{"~2021.10", "2021.11.0", false},
+		{"^2021.01.0", "2021.12.9", true},
+		{"^2021.01.0", "2022.01.0", false},
+		{"2021.01.02", "2021.1.2", true},
+		{">2021.10.1", "2021.10.1-rc.1", false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.versionStr+" § "+tt.constraintStr, runnableSchemeScenario(scheme, tt))
+	}
+}
+
+func TestCalVerScheme_Format(t *testing.T) {
+	scheme := mustNewScheme(t, semver.SchemeCalVer)
+
+	testCases := map[string]string{
+		"2021.10.1":       "2021.10.1",
+		"2021.01":         "2021.01.0",
+		"2021.1.01":       "2021.01.1",
+		"2021.10.1-rc.1":  "2021.10.1-rc.1",
+		"2021.10+abcdef0": "2021.10.0+abcdef0",
+	}
+
+	for versionStr, want := range testCases {
+		version, err := scheme.ParseVersion(versionStr)
+		if err != nil {
+			t.Fatalf("unexpected error, got %v", err)
+		}
+
+		got := scheme.Format(version)
+		if got != want {
+			t.Fatalf("expected [%++v], got [%++v]", want, got)
+		}
+	}
+}
+
+func TestInvalidCalVerScheme(t *testing.T) {
+	scheme := mustNewScheme(t, semver.SchemeCalVer)
+
+	versionStrings := []string{
+		"1.2.3",
+		"21.10.1",
+		"2021",
+		"2021.13.1",
+		"2021.00.1",
+		"2021.10.1.1",
+		"v2021.10.1",
+	}
+
+	for _, versionStr := range versionStrings {
+		t.Run(versionStr, runnableInvalidSchemeVersion(scheme, versionStr))
+	}
+}
+
+func TestCalVerScheme_Compare(t *testing.T) {
+	scheme := mustNewScheme(t, semver.SchemeCalVer)
+
+	low, err := scheme.ParseVersion("2021.09.3")
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	high, err := scheme.ParseVersion("2021.10")
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if scheme.Compare(low, high) != 1 {
+		t.Fatalf("expected %s to be lower than %s", low, high)
+	}
+}
@@ -0,0 +1,167 @@
+package semver
+
+import (
+	"encoding/json"
+)
+
+// V wraps a Version value to provide text, JSON and YAML encoding support, so
+// versions can be embedded directly on structs. Values are validated when
+// decoded
+type V struct {
+	Version
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (source V) MarshalText() ([]byte, error) {
+	if source.Version == nil {
+		return []byte{}, nil
+	}
+
+	return []byte(source.Version.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Empty text decodes to the
+// zero value, as encoded by MarshalText
+func (source *V) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		source.Version = nil
+		return nil
+	}
+
+	version, err := NewVersion(string(text))
+	if err != nil {
+		return err
+	}
+
+	source.Version = version
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (source V) MarshalJSON() ([]byte, error) {
+	if source.Version == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(source.Version.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (source *V) UnmarshalJSON(data []byte) error {
+	var versionStr *string
+
+	err := json.Unmarshal(data, &versionStr)
+	if err != nil {
+		return err
+	}
+
+	if versionStr == nil {
+		source.Version = nil
+		return nil
+	}
+
+	return source.UnmarshalText([]byte(*versionStr))
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (source V) MarshalYAML() (interface{}, error) {
+	if source.Version == nil {
+		return nil, nil
+	}
+
+	return source.Version.String(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (source *V) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var versionStr string
+
+	err := unmarshal(&versionStr)
+	if err != nil {
+		return err
+	}
+
+	return source.UnmarshalText([]byte(versionStr))
+}
+
+// C wraps a Constraint value to provide text, JSON and YAML encoding support,
+// so constraints can be embedded directly on structs. Values are validated
+// when decoded
+type C struct {
+	Constraint
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (source C) MarshalText() ([]byte, error) {
+	if source.Constraint == nil {
+		return []byte{}, nil
+	}
+
+	return []byte(source.Constraint.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Empty text decodes to the
+// zero value, as encoded by MarshalText
+func (source *C) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		source.Constraint = nil
+		return nil
+	}
+
+	constraint, err := NewConstraint(string(text))
+	if err != nil {
+		return err
+	}
+
+	source.Constraint = constraint
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (source C) MarshalJSON() ([]byte, error) {
+	if source.Constraint == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(source.Constraint.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (source *C) UnmarshalJSON(data []byte) error {
+	var constraintStr *string
+
+	err := json.Unmarshal(data, &constraintStr)
+	if err != nil {
+		return err
+	}
+
+	if constraintStr == nil {
+		source.Constraint = nil
+		return nil
+	}
+
+	return source.UnmarshalText([]byte(*constraintStr))
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (source C) MarshalYAML() (interface{}, error) {
+	if source.Constraint == nil {
+		return nil, nil
+	}
+
+	return source.Constraint.String(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (source *C) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var constraintStr string
+
+	err := unmarshal(&constraintStr)
+	if err != nil {
+		return err
+	}
+
+	return source.UnmarshalText([]byte(constraintStr))
+}
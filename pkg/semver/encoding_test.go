@@ -0,0 +1,219 @@
+package semver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/wwmoraes/maker/pkg/semver"
+	"gopkg.in/yaml.v2"
+)
+
+type encodingScenario struct {
+	Version    semver.V `json:"version" yaml:"version"`
+	Constraint semver.C `json:"constraint" yaml:"constraint"`
+}
+
+func TestEncoding_Text(t *testing.T) {
+	var version semver.V
+
+	err := version.UnmarshalText([]byte("1.2.3-alpha.1"))
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	gotText, err := version.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if string(gotText) != "1.2.3-alpha.1" {
+		t.Fatalf("expected [%++v], got [%++v]", "1.2.3-alpha.1", string(gotText))
+	}
+
+	var constraint semver.C
+
+	err = constraint.UnmarshalText([]byte("^1.2"))
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	gotText, err = constraint.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if string(gotText) != "^1.2" {
+		t.Fatalf("expected [%++v], got [%++v]", "^1.2", string(gotText))
+	}
+}
+
+func TestEncoding_TextZero(t *testing.T) {
+	version := semver.V{Version: mustNewVersion(t, "1.2.3")}
+
+	gotText, err := semver.V{}.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	err = version.UnmarshalText(gotText)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if version.Version != nil {
+		t.Fatalf("expected nil version, got %++v", version)
+	}
+
+	constraint := semver.C{Constraint: mustNewSpecificConstraint(t, "^1.2", semver.NewConstraint)}
+
+	gotText, err = semver.C{}.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	err = constraint.UnmarshalText(gotText)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if constraint.Constraint != nil {
+		t.Fatalf("expected nil constraint, got %++v", constraint)
+	}
+}
+
+func TestEncoding_JSON(t *testing.T) {
+	var scenario encodingScenario
+
+	err := json.Unmarshal([]byte(`{"version":"1.2.3","constraint":"~1.2"}`), &scenario)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if !scenario.Constraint.Match(scenario.Version, false) {
+		t.Fatalf("expected %s to match %s", scenario.Constraint, scenario.Version)
+	}
+
+	gotData, err := json.Marshal(&scenario)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	wantData := `{"version":"1.2.3","constraint":"~1.2"}`
+	if string(gotData) != wantData {
+		t.Fatalf("expected [%++v], got [%++v]", wantData, string(gotData))
+	}
+}
+
+func TestEncoding_JSONNull(t *testing.T) {
+	var scenario encodingScenario
+
+	err := json.Unmarshal([]byte(`{"version":null,"constraint":null}`), &scenario)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if scenario.Version.Version != nil || scenario.Constraint.Constraint != nil {
+		t.Fatalf("expected nil values, got %++v", scenario)
+	}
+
+	gotData, err := json.Marshal(&scenario)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	wantData := `{"version":null,"constraint":null}`
+	if string(gotData) != wantData {
+		t.Fatalf("expected [%++v], got [%++v]", wantData, string(gotData))
+	}
+}
+
+func TestEncoding_YAML(t *testing.T) {
+	var scenario encodingScenario
+
+	err := yaml.Unmarshal([]byte("version: 1.2.3\nconstraint: '>=1.0.0 <2.0.0'\n"), &scenario)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if !scenario.Constraint.Match(scenario.Version, false) {
+		t.Fatalf("expected %s to match %s", scenario.Constraint, scenario.Version)
+	}
+
+	gotData, err := yaml.Marshal(&scenario)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	wantData := "version: 1.2.3\nconstraint: '>=1.0.0 <2.0.0'\n"
+	if string(gotData) != wantData {
+		t.Fatalf("expected [%++v], got [%++v]", wantData, string(gotData))
+	}
+}
+
+func TestEncoding_YAMLZero(t *testing.T) {
+	gotData, err := yaml.Marshal(&encodingScenario{})
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	scenario := encodingScenario{
+		Version:    semver.V{Version: mustNewVersion(t, "1.2.3")},
+		Constraint: semver.C{Constraint: mustNewSpecificConstraint(t, "^1.2", semver.NewConstraint)},
+	}
+
+	err = yaml.Unmarshal(gotData, &scenario)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if scenario.Version.Version != nil || scenario.Constraint.Constraint != nil {
+		t.Fatalf("expected nil values, got %++v", scenario)
+	}
+}
+
+func TestEncoding_Invalid(t *testing.T) {
+	testCases := map[string]func() error{
+		"text version": func() error {
+			var version semver.V
+			return version.UnmarshalText([]byte("1.2"))
+		},
+		"text constraint": func() error {
+			var constraint semver.C
+			return constraint.UnmarshalText([]byte("~aaa"))
+		},
+		"json version": func() error {
+			var scenario encodingScenario
+			return json.Unmarshal([]byte(`{"version":"a.2.3"}`), &scenario)
+		},
+		"json constraint": func() error {
+			var scenario encodingScenario
+			return json.Unmarshal([]byte(`{"constraint":"1.2.3.4"}`), &scenario)
+		},
+		"yaml version": func() error {
+			var scenario encodingScenario
+			return yaml.Unmarshal([]byte("version: 1.2.3.4\n"), &scenario)
+		},
+		"yaml constraint": func() error {
+			var scenario encodingScenario
+			return yaml.Unmarshal([]byte("constraint: master\n"), &scenario)
+		},
+	}
+
+	for name, decode := range testCases {
+		decode := decode
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := decode()
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			var parseErr *semver.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected an error wrapped with ParseError, instead got a plain %++v", err.Error())
+			}
+		})
+	}
+}
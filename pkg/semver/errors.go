@@ -12,6 +12,8 @@ var (
 	// ErrInvalidVersion means a version string that does not comply with the
 	// semantic version specification
 	ErrInvalidVersion = errors.New("invalid version string")
+	// ErrUnknownScheme means a version scheme name that is not registered
+	ErrUnknownScheme = errors.New("unknown version scheme")
 )
 
 // ParseError is returned by Version and Constraint constructors when they fail
@@ -0,0 +1,113 @@
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pseudoVersionTimeFormat is the UTC timestamp layout used on pseudo-versions
+const pseudoVersionTimeFormat = "20060102150405"
+
+// pseudoVersionRule matches the prerelease suffix of Go module pseudo-versions,
+// on all its forms: vX.0.0-yyyymmddhhmmss-abcdefabcdef,
+// vX.Y.Z-pre.0.yyyymmddhhmmss-abcdefabcdef and
+// vX.Y.(Z+1)-0.yyyymmddhhmmss-abcdefabcdef
+var pseudoVersionRule = regexp.MustCompile(`(?:^|\.)(?P<time>\d{14})-(?P<revision>[0-9a-f]{12})$`)
+
+// versionLiteralPrefixRule matches the optional "v" prefix of version literals
+// within constraints
+var versionLiteralPrefixRule = regexp.MustCompile(`(^|[\s<=>~^|])v(\d)`)
+
+// NewPseudoVersion returns a Go module pseudo-version for a revision that has
+// no preceding tagged version, e.g. v0.0.0-20211018120000-abcdef123456
+func NewPseudoVersion(t time.Time, revision string) (Version, error) {
+	if len(revision) < 12 {
+		return nil, &ParseError{
+			Func:  "NewPseudoVersion",
+			Input: revision,
+			Err:   ErrInvalidIdentifier,
+		}
+	}
+
+	return NewVersion(fmt.Sprintf("0.0.0-%s-%s", t.UTC().Format(pseudoVersionTimeFormat), revision[:12]))
+}
+
+// IsPseudoVersion returns true if the version is a Go module pseudo-version
+func IsPseudoVersion(version Version) bool {
+	return version.IsPrerelease() && pseudoVersionRule.MatchString(version.Prerelease().String())
+}
+
+// PseudoVersionRevision returns the abbreviated revision identifier of a Go
+// module pseudo-version
+func PseudoVersionRevision(version Version) (string, error) {
+	matches := pseudoVersionRule.FindStringSubmatch(version.Prerelease().String())
+	if matches == nil {
+		return "", &ParseError{
+			Func:  "PseudoVersionRevision",
+			Input: version.String(),
+			Err:   ErrInvalidVersion,
+		}
+	}
+
+	return matches[2], nil
+}
+
+// PseudoVersionTime returns the revision commit time of a Go module
+// pseudo-version
+func PseudoVersionTime(version Version) (time.Time, error) {
+	matches := pseudoVersionRule.FindStringSubmatch(version.Prerelease().String())
+	if matches == nil {
+		return time.Time{}, &ParseError{
+			Func:  "PseudoVersionTime",
+			Input: version.String(),
+			Err:   ErrInvalidVersion,
+		}
+	}
+
+	return time.Parse(pseudoVersionTimeFormat, matches[1])
+}
+
+// pseudoScheme implements Go module versioning, which are semantic versions
+// prefixed with "v" that also identify untagged revisions with
+// pseudo-versions (e.g. v1.2.3 or v0.0.0-20211018120000-abcdef123456)
+type pseudoScheme struct{}
+
+func (scheme *pseudoScheme) Name() string {
+	return SchemePseudo
+}
+
+func (scheme *pseudoScheme) ParseVersion(versionStr string) (Version, error) {
+	version, err := NewVersion(strings.TrimPrefix(versionStr, "v"))
+	if err != nil {
+		return nil, &ParseError{
+			Func:  "pseudo.ParseVersion",
+			Input: versionStr,
+			Err:   err,
+		}
+	}
+
+	return version, nil
+}
+
+func (scheme *pseudoScheme) ParseConstraint(constraintStr string) (Constraint, error) {
+	constraint, err := NewConstraint(versionLiteralPrefixRule.ReplaceAllString(constraintStr, "$1$2"))
+	if err != nil {
+		return nil, &ParseError{
+			Func:  "pseudo.ParseConstraint",
+			Input: constraintStr,
+			Err:   err,
+		}
+	}
+
+	return constraint, nil
+}
+
+func (scheme *pseudoScheme) Compare(source, target Version) int {
+	return source.Compare(target)
+}
+
+func (scheme *pseudoScheme) Format(version Version) string {
+	return fmt.Sprintf("v%s", version.String())
+}
@@ -0,0 +1,125 @@
+package semver_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wwmoraes/maker/pkg/semver"
+)
+
+func TestNewPseudoVersion(t *testing.T) {
+	commitTime := time.Date(2021, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+	version, err := semver.NewPseudoVersion(commitTime, "abcdef1234567890abcdef1234567890abcdef12")
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	want := "v0.0.0-20211018120000-abcdef123456"
+	got := mustNewScheme(t, semver.SchemePseudo).Format(version)
+	if got != want {
+		t.Fatalf("expected [%++v], got [%++v]", want, got)
+	}
+
+	if !semver.IsPseudoVersion(version) {
+		t.Fatalf("expected %s to be a pseudo-version", got)
+	}
+
+	revision, err := semver.PseudoVersionRevision(version)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if revision != "abcdef123456" {
+		t.Fatalf("expected [%++v], got [%++v]", "abcdef123456", revision)
+	}
+
+	gotTime, err := semver.PseudoVersionTime(version)
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if !gotTime.Equal(commitTime) {
+		t.Fatalf("expected [%++v], got [%++v]", commitTime, gotTime)
+	}
+}
+
+func TestInvalidNewPseudoVersion(t *testing.T) {
+	_, err := semver.NewPseudoVersion(time.Now(), "abcdef")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestIsPseudoVersion(t *testing.T) {
+	scheme := mustNewScheme(t, semver.SchemePseudo)
+
+	testCases := map[string]bool{
+		"v0.0.0-20211018120000-abcdef123456":       true,
+		"v1.2.4-0.20211018120000-abcdef123456":     true,
+		"v1.2.3-pre.0.20211018120000-abcdef123456": true,
+		"v1.2.3":                false,
+		"v1.2.3-rc.1":           false,
+		"v1.2.3-20211018120000": false,
+		"v0.0.0-20211018120000-abcdef123456+build1": true,
+	}
+
+	for versionStr, want := range testCases {
+		version, err := scheme.ParseVersion(versionStr)
+		if err != nil {
+			t.Fatalf("unexpected error, got %v", err)
+		}
+
+		got := semver.IsPseudoVersion(version)
+		if got != want {
+			t.Fatalf("%s: expected [%++v], got [%++v]", versionStr, want, got)
+		}
+	}
+}
+
+func TestPseudoScheme(t *testing.T) {
+	scheme := mustNewScheme(t, semver.SchemePseudo)
+
+	testCases := []schemeScenario{
+		{"v0.0.0-20211018120000-abcdef123456", "v0.0.0-20211018120000-abcdef123456", true},
+		{"=v0.0.0-20211018120000-abcdef123456", "v0.0.0-20211018120000-abcdef123456", true},
+		{"=0.0.0-20211018120000-abcdef123456", "v0.0.0-20211018120000-abcdef123456", true},
+		{"v0.0.0-20211018120000-abcdef123456", "v0.0.0-20211019120000-abcdef123456", false},
+		{"^v1.2", "v1.4.0", true},
+		{">=v1.2.0 <v2.0.0", "v1.9.9", true},
+		{"v1.0.0 - v2.0.0", "v2.0.1", false},
+		{"*", "1.0.0", true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.versionStr+" § "+tt.constraintStr, runnableSchemeScenario(scheme, tt))
+	}
+
+	older, err := scheme.ParseVersion("v0.0.0-20211018120000-abcdef123456")
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	newer, err := scheme.ParseVersion("v0.0.0-20211019120000-123456abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	if scheme.Compare(older, newer) != 1 {
+		t.Fatalf("expected %s to be lower than %s", older, newer)
+	}
+}
+
+func TestInvalidPseudoScheme(t *testing.T) {
+	scheme := mustNewScheme(t, semver.SchemePseudo)
+
+	versionStrings := []string{
+		"v1.2",
+		"vv1.2.3",
+		"v1.2.3.4",
+	}
+
+	for _, versionStr := range versionStrings {
+		t.Run(versionStr, runnableInvalidSchemeVersion(scheme, versionStr))
+	}
+}
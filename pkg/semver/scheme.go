@@ -0,0 +1,103 @@
+package semver
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// SchemeSemVer is the name of the semantic versioning scheme
+	SchemeSemVer = "semver"
+	// SchemeCalVer is the name of the calendar versioning scheme
+	SchemeCalVer = "calver"
+	// SchemePseudo is the name of the Go module pseudo-versioning scheme
+	SchemePseudo = "pseudo"
+)
+
+// VersionScheme is implemented by versioning conventions that are able to
+// parse, compare and constrain their versions
+type VersionScheme interface {
+	// Name returns the scheme identifier, as used on configuration files
+	Name() string
+	// ParseVersion creates a version value with the provided string, granted
+	// that it is valid within the scheme
+	ParseVersion(versionStr string) (Version, error)
+	// ParseConstraint returns a new version rule with the given constraint
+	// string, granted that its versions are valid within the scheme
+	ParseConstraint(constraintStr string) (Constraint, error)
+	// Compare returns an integer comparing two versions lexicographically.
+	// The result will be 0 if target == source, -1 if target < source, and +1 if
+	// target > source.
+	Compare(source, target Version) int
+	// Format returns the version string as written within the scheme
+	Format(version Version) string
+}
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]VersionScheme{
+		SchemeSemVer: &semverScheme{},
+		SchemeCalVer: &calverScheme{},
+		SchemePseudo: &pseudoScheme{},
+	}
+)
+
+// RegisterScheme makes a version scheme available by its name. Registering a
+// scheme with the same name as an existing one replaces it
+func RegisterScheme(scheme VersionScheme) error {
+	if scheme == nil {
+		return fmt.Errorf("no version scheme provided")
+	}
+
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+
+	schemes[scheme.Name()] = scheme
+
+	return nil
+}
+
+// NewScheme returns the registered version scheme with the given name. An
+// empty name returns the semantic versioning scheme
+func NewScheme(name string) (VersionScheme, error) {
+	if name == "" {
+		name = SchemeSemVer
+	}
+
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+
+	scheme, exists := schemes[name]
+	if !exists {
+		return nil, &ParseError{
+			Func:  "NewScheme",
+			Input: name,
+			Err:   ErrUnknownScheme,
+		}
+	}
+
+	return scheme, nil
+}
+
+// semverScheme implements the semantic versioning specification
+type semverScheme struct{}
+
+func (scheme *semverScheme) Name() string {
+	return SchemeSemVer
+}
+
+func (scheme *semverScheme) ParseVersion(versionStr string) (Version, error) {
+	return NewVersion(versionStr)
+}
+
+func (scheme *semverScheme) ParseConstraint(constraintStr string) (Constraint, error) {
+	return NewConstraint(constraintStr)
+}
+
+func (scheme *semverScheme) Compare(source, target Version) int {
+	return source.Compare(target)
+}
+
+func (scheme *semverScheme) Format(version Version) string {
+	return version.String()
+}
@@ -0,0 +1,148 @@
+package semver_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wwmoraes/maker/pkg/semver"
+)
+
+type schemeScenario struct {
+	constraintStr string
+	versionStr    string
+	want          bool
+}
+
+func mustNewScheme(tb testing.TB, name string) semver.VersionScheme {
+	tb.Helper()
+
+	scheme, err := semver.NewScheme(name)
+	if err != nil {
+		tb.Fatalf("unexpected error, got %v", err)
+	}
+
+	if scheme == nil {
+		tb.Fatal("expected valid scheme, got nil")
+	}
+
+	return scheme
+}
+
+func runnableSchemeScenario(scheme semver.VersionScheme, tt schemeScenario) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		constraint, err := scheme.ParseConstraint(tt.constraintStr)
+		if err != nil {
+			t.Fatalf("unexpected error, got %v", err)
+		}
+
+		version, err := scheme.ParseVersion(tt.versionStr)
+		if err != nil {
+			t.Fatalf("unexpected error, got %v", err)
+		}
+
+		got := constraint.Match(version, false)
+		if got != tt.want {
+			t.Fatalf("got %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func runnableInvalidSchemeVersion(scheme semver.VersionScheme, versionStr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		version, err := scheme.ParseVersion(versionStr)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+
+		if !errors.Is(err, semver.ErrInvalidVersion) {
+			t.Fatalf("expected error [%++v], got [%++v]", semver.ErrInvalidVersion, err)
+		}
+
+		if version != nil {
+			t.Fatal("expected nil, got", version)
+		}
+	}
+}
+
+func TestNewScheme(t *testing.T) {
+	testCases := map[string]string{
+		"":                  semver.SchemeSemVer,
+		semver.SchemeSemVer: semver.SchemeSemVer,
+		semver.SchemeCalVer: semver.SchemeCalVer,
+		semver.SchemePseudo: semver.SchemePseudo,
+	}
+
+	for name, want := range testCases {
+		scheme := mustNewScheme(t, name)
+
+		if scheme.Name() != want {
+			t.Fatalf("expected [%++v], got [%++v]", want, scheme.Name())
+		}
+	}
+}
+
+func TestInvalidNewScheme(t *testing.T) {
+	scheme, err := semver.NewScheme("lorem")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if !errors.Is(err, semver.ErrUnknownScheme) {
+		t.Fatalf("expected error [%++v], got [%++v]", semver.ErrUnknownScheme, err)
+	}
+
+	if scheme != nil {
+		t.Fatal("expected nil, got", scheme)
+	}
+}
+
+type customScheme struct {
+	semver.VersionScheme
+}
+
+func (scheme *customScheme) Name() string {
+	return "custom"
+}
+
+func TestRegisterScheme(t *testing.T) {
+	err := semver.RegisterScheme(&customScheme{mustNewScheme(t, semver.SchemeSemVer)})
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	scheme := mustNewScheme(t, "custom")
+	if scheme.Name() != "custom" {
+		t.Fatalf("expected [%++v], got [%++v]", "custom", scheme.Name())
+	}
+
+	err = semver.RegisterScheme(nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestSemVerScheme(t *testing.T) {
+	scheme := mustNewScheme(t, semver.SchemeSemVer)
+
+	testCases := []schemeScenario{
+		{"^1.2", "1.4.0", true},
+		{"^1.2", "2.0.0", false},
+		{"~1.2.3", "1.2.9", true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.versionStr+" § "+tt.constraintStr, runnableSchemeScenario(scheme, tt))
+	}
+
+	t.Run("v1.2.3", runnableInvalidSchemeVersion(scheme, "v1.2.3"))
+
+	low := mustNewVersion(t, "1.2.3")
+	high := mustNewVersion(t, "1.10.0")
+	if scheme.Compare(low, high) != 1 {
+		t.Fatalf("expected %s to be lower than %s", low, high)
+	}
+}
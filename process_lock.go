@@ -0,0 +1,152 @@
+package maker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// ProcessLockDirectory is the directory, relative to the user cache
+	// directory, with the files that serialize concurrent maker processes, one
+	// per project. They are kept out of the projects so they are never committed
+	ProcessLockDirectory = "maker/locks"
+	// DefaultLockTimeout is how long to wait for other maker processes to
+	// release the project lock
+	DefaultLockTimeout = 30 * time.Second
+)
+
+const lockRetryInterval = 100 * time.Millisecond
+
+// ErrLocked means the project is locked by another process
+var ErrLocked = errors.New("locked by another process")
+
+// ProcessLock is an exclusive advisory lock on a file, held by a single process
+// at a time. The OS releases it if the process exits without doing so
+type ProcessLock struct {
+	fd *os.File
+}
+
+// processLockFilename returns the process lock file of the snippets directory,
+// on the user cache directory, or the temporary one if there is none
+func processLockFilename(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// the same project may be reached through symbolic links
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+
+	locksDir := filepath.Join(base, filepath.FromSlash(ProcessLockDirectory))
+
+	err = os.MkdirAll(locksDir, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(locksDir, fmt.Sprintf("%x.lock", sha256.Sum256([]byte(dir)))), nil
+}
+
+// AcquireProcessLock locks the file, creating it if needed, and records the
+// current process ID on it. It waits up to the timeout for other processes to
+// release the lock, and returns an error wrapping ErrLocked with the holder
+// process ID, if known, when the timeout expires
+func AcquireProcessLock(filename string, timeout time.Duration) (*ProcessLock, error) {
+	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		locked, err := tryLockFile(fd)
+		if err != nil {
+			fd.Close()
+			return nil, err
+		}
+
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			pid := lockHolder(fd)
+			fd.Close()
+
+			if pid == 0 {
+				return nil, fmt.Errorf("%s is %w, gave up after %s", filename, ErrLocked, timeout)
+			}
+
+			return nil, fmt.Errorf("%s is %w (pid %d), gave up after %s", filename, ErrLocked, pid, timeout)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+
+	err = fd.Truncate(0)
+	if err == nil {
+		_, err = fd.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	if err != nil {
+		unlockFile(fd)
+		fd.Close()
+		return nil, err
+	}
+
+	return &ProcessLock{fd: fd}, nil
+}
+
+// Close releases the lock. It is safe to call multiple times
+func (lock *ProcessLock) Close() error {
+	if lock.fd == nil {
+		return nil
+	}
+
+	fd := lock.fd
+	lock.fd = nil
+
+	// clear the holder process ID before anyone else can acquire it
+	_ = fd.Truncate(0)
+
+	err := unlockFile(fd)
+	closeErr := fd.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+// lockHolder returns the process ID recorded on the lock file, or zero if it
+// is not available
+func lockHolder(fd *os.File) int {
+	data, err := io.ReadAll(io.NewSectionReader(fd, 0, 32))
+	if err != nil {
+		return 0
+	}
+
+	pid, err := strconv.Atoi(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return 0
+	}
+
+	return pid
+}
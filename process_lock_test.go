@@ -0,0 +1,93 @@
+package maker_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wwmoraes/maker"
+)
+
+func TestProcessLockTimeout(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "process.lock")
+
+	held, err := maker.AcquireProcessLock(filename, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	_, err = maker.AcquireProcessLock(filename, 200*time.Millisecond)
+	if !errors.Is(err, maker.ErrLocked) {
+		t.Fatalf("got error %v, want %v", err, maker.ErrLocked)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("gave up after %s, before the timeout", elapsed)
+	}
+
+	if want := fmt.Sprintf("(pid %d)", os.Getpid()); !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, want it to contain %q", err, want)
+	}
+
+	err = held.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released, err := maker.AcquireProcessLock(filename, 0)
+	if err != nil {
+		t.Fatalf("got error %v acquiring a released lock", err)
+	}
+
+	err = released.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewDefaultProcessLock(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	root := t.TempDir()
+
+	held, err := maker.NewDefault(maker.WithRoot(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = maker.NewDefault(maker.WithRoot(root), maker.WithLockTimeout(0))
+	if !errors.Is(err, maker.ErrLocked) {
+		t.Fatalf("got error %v, want %v", err, maker.ErrLocked)
+	}
+
+	err = held.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released, err := maker.NewDefault(maker.WithRoot(root), maker.WithLockTimeout(0))
+	if err != nil {
+		t.Fatalf("got error %v opening a released project", err)
+	}
+
+	err = released.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lock file is not part of the project
+	entries, err := os.ReadDir(filepath.Join(root, maker.SnippetsDirectory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("got files %v on the snippets directory, want none", entries)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package maker
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// tryLockFile applies an exclusive advisory lock on the file without blocking,
+// returning false if another process holds it
+func tryLockFile(fd *os.File) (bool, error) {
+	err := unix.Flock(int(fd.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// unlockFile removes the advisory lock from the file
+func unlockFile(fd *os.File) error {
+	return unix.Flock(int(fd.Fd()), unix.LOCK_UN)
+}
@@ -0,0 +1,36 @@
+//go:build windows
+// +build windows
+
+package maker
+
+import (
+	"errors"
+	"math"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is where the locked byte range starts. It is placed past any
+// content, so the holder process ID remains readable by other processes
+const lockOffset = math.MaxUint32
+
+// tryLockFile applies an exclusive lock on the file without blocking,
+// returning false if another process holds it
+func tryLockFile(fd *os.File) (bool, error) {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffset}
+
+	err := windows.LockFileEx(windows.Handle(fd.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// unlockFile removes the lock from the file
+func unlockFile(fd *os.File) error {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffset}
+
+	return windows.UnlockFileEx(windows.Handle(fd.Fd()), 0, 1, 0, overlapped)
+}
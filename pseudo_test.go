@@ -0,0 +1,136 @@
+package maker_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/wwmoraes/maker"
+)
+
+const pseudoConfig = `repositories:
+- alias: test
+  url: https://example.com/snippets.git
+  scheme: pseudo
+  snippets: {}
+`
+
+// commitsRepository returns a factory for an in-memory repository with one
+// untagged commit per snippet contents, an hour apart from each other, and
+// the hashes of the commits
+func commitsRepository(t *testing.T, contents ...string) (maker.RepositoryFactory, []string) {
+	t.Helper()
+
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := make([]string, 0, len(contents))
+
+	for index, data := range contents {
+		err = util.WriteFile(worktree.Filesystem, "snippets/go.mk", []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = worktree.Add("snippets/go.mk")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		when := time.Date(2026, 10, 18, 12+index, 0, 0, 0, time.FixedZone("test", 2*60*60))
+
+		hash, err := worktree.Commit(data, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: when},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hashes = append(hashes, hash.String())
+	}
+
+	source := maker.NewGitSource(repo)
+
+	return func(options maker.FetchOptions) (maker.Source, error) {
+		return source, nil
+	}, hashes
+}
+
+func TestPseudoVersions(t *testing.T) {
+	factory, hashes := commitsRepository(t, "one\n", "two\n")
+
+	first := "v0.0.0-20261018100000-" + hashes[0][:12]
+	second := "v0.0.0-20261018110000-" + hashes[1][:12]
+
+	tests := []struct {
+		name    string
+		snippet string
+		pin     string
+		version string
+		data    string
+		err     string
+	}{
+		{name: "head", snippet: "go", pin: "'*'", version: second, data: "two\n"},
+		{name: "commit", snippet: "go@commit:" + hashes[0][:7], pin: "commit:" + hashes[0][:7], version: first, data: "one\n"},
+		{name: "pseudo-version", snippet: "go@" + first, pin: first, version: first, data: "one\n"},
+		{name: "pseudo-version time", snippet: "go@v0.0.0-20261018120000-" + hashes[0][:12], err: "does not match the commit time"},
+	}
+
+	for _, test := range tests {
+		conf := maker.NewMemoryFile([]byte(pseudoConfig))
+		lock := maker.NewMemoryFile(nil)
+		directory := memfs.New()
+
+		mk, err := maker.New(conf, lock, directory, maker.WithRepositoryFactory(factory))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mk.Add(test.snippet)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if !bytes.Contains(conf.Bytes(), []byte("go: "+test.pin+"\n")) {
+			t.Errorf("%s: configuration does not contain the pin %s:\n%s", test.name, test.pin, conf.Bytes())
+		}
+
+		if !bytes.Contains(lock.Bytes(), []byte("version: "+test.version+"\n")) {
+			t.Errorf("%s: lock does not contain the version %s:\n%s", test.name, test.version, lock.Bytes())
+		}
+
+		assertSnippet(t, directory, test.data)
+
+		// pins are read back as written, so they are locked already
+		mk, err = maker.New(maker.NewMemoryFile(conf.Bytes()), maker.NewMemoryFile(lock.Bytes()), directory, maker.WithRepositoryFactory(factory))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mk.InstallFrozen(maker.StrategyFail)
+		if err != nil {
+			t.Errorf("%s: got error %v installing the frozen lock", test.name, err)
+		}
+	}
+}
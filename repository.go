@@ -1,114 +1,160 @@
 package maker
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"sync"
 
-	git "github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/storer"
-	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/wwmoraes/maker/pkg/semver"
 )
 
-type FileReader interface {
-	ID() plumbing.Hash
-	Reader() (io.ReadCloser, error)
-}
-
 type Repository struct {
-	*git.Repository `yaml:"-"`
-
-	Snippets map[string]string `yaml:"snippets"`
-	Alias    string            `yaml:"alias,omitempty"`
-	URL      string            `yaml:"url"`
-
-	sync.Mutex `yaml:"-"`
+	// Source provides the repository snippets once initialized
+	Source `yaml:"-"`
+
+	Snippets map[string]Pin `yaml:"snippets"`
+	Alias    string         `yaml:"alias,omitempty"`
+	URL      string         `yaml:"url"`
+	Scheme   string         `yaml:"scheme,omitempty"`
+	Auth     *Auth          `yaml:"auth,omitempty"`
 }
 
-func (repository *Repository) Init() error {
-	if repository.Repository != nil {
-		return nil
+// UnmarshalYAML implements yaml.Unmarshaler, parsing the snippet pins with the
+// repository version scheme
+func (repository *Repository) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var data struct {
+		Snippets map[string]string `yaml:"snippets"`
+		Alias    string            `yaml:"alias,omitempty"`
+		URL      string            `yaml:"url"`
+		Scheme   string            `yaml:"scheme,omitempty"`
+		Auth     *Auth             `yaml:"auth,omitempty"`
 	}
 
-	repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
-		URL: repository.URL,
-	})
+	err := unmarshal(&data)
+	if err != nil {
+		return err
+	}
+
+	repository.Alias = data.Alias
+	repository.URL = data.URL
+	repository.Scheme = data.Scheme
+	repository.Auth = data.Auth
+
+	scheme, err := repository.VersionScheme()
 	if err != nil {
 		return err
 	}
 
-	repository.Repository = repo
+	repository.Snippets = make(map[string]Pin, len(data.Snippets))
+	for name, pinStr := range data.Snippets {
+		pin, err := parseConfigPin(pinStr, scheme)
+		if err != nil {
+			return fmt.Errorf("snippet %s: %w", name, err)
+		}
+
+		repository.SetSnippet(name, pin)
+	}
+
 	return nil
 }
 
-func (repository *Repository) changeReference(reference string) error {
-	_, err := repository.ResolveRevision(plumbing.Revision(reference))
+// VersionScheme returns the scheme used by the repository versions, which
+// defaults to semantic versioning
+func (repository *Repository) VersionScheme() (semver.VersionScheme, error) {
+	return semver.NewScheme(repository.Scheme)
+}
 
-	return err
+// Init opens the repository source, if not opened yet
+func (repository *Repository) Init() error {
+	return repository.InitWithProgress(nil)
 }
 
-func (repository *Repository) isOnReference(reference string) (bool, error) {
-	currentReference, err := repository.Head()
-	if err != nil {
-		return false, err
-	}
+// InitContext fetches the repository like Init, stopping if the context is
+// done. Snippets read later are fetched with the same context, if needed
+func (repository *Repository) InitContext(ctx context.Context) error {
+	return repository.initFrom(ctx, repository.URL, repository.Auth, OpenSource, nil)
+}
 
-	if currentReference.Target().String() == reference {
-		return true, nil
-	}
+// InitWithProgress fetches the repository like Init, writing the
+// remote progress output to progress
+func (repository *Repository) InitWithProgress(progress io.Writer) error {
+	return repository.InitWith(OpenSource, progress)
+}
 
-	if currentReference.Hash().String() == reference {
-		return true, nil
+// FetchOptions describes how a RepositoryFactory fetches a repository
+type FetchOptions struct {
+	URL string
+	// Auth authenticates against the remote, if not nil
+	Auth transport.AuthMethod
+	// Progress receives the remote progress output, if not nil
+	Progress io.Writer
+	// HTTPClient fetches non-git sources over HTTP(S), if not nil
+	HTTPClient *http.Client
+	// CacheDirectory keeps git repositories across runs, if set, instead of
+	// cloning them into memory every time
+	CacheDirectory string
+	// Context cancels the fetch, and the later ones the source makes lazily,
+	// if not nil
+	Context context.Context
+}
+
+// ctx returns the options context, or the background one if unset
+func (options FetchOptions) ctx() context.Context {
+	if options.Context == nil {
+		return context.Background()
 	}
 
-	return false, err
+	return options.Context
 }
 
-// Get returns the snippet file contents reader. The caller is responsible for
-// closing it after usage.
-func (repository *Repository) Get(reference, name string) (FileReader, error) {
-	// lock to ensure we retrieve the file from the proper reference
-	repository.Lock()
-	defer repository.Unlock()
+// RepositoryFactory returns the snippets source described by the options
+type RepositoryFactory func(options FetchOptions) (Source, error)
 
-	is, err := repository.isOnReference(reference)
-	if err != nil {
-		return nil, err
-	}
+// InitWith sets up the repository with the factory, if not set up yet,
+// authenticating as set by the repository Auth
+func (repository *Repository) InitWith(factory RepositoryFactory, progress io.Writer) error {
+	return repository.initFrom(context.Background(), repository.URL, repository.Auth, factory, progress)
+}
 
-	if !is {
-		err = repository.changeReference(reference)
-		if err != nil {
-			return nil, err
-		}
+// initFrom sets up the repository from the URL, which may differ from the
+// canonical one, such as a mirror, authenticating with the auth settings
+func (repository *Repository) initFrom(ctx context.Context, url string, settings *Auth, factory RepositoryFactory, progress io.Writer) error {
+	if repository.Source != nil {
+		return nil
 	}
 
-	trees, err := repository.TreeObjects()
+	_, location := splitSourceURL(url)
+
+	auth, err := settings.TransportAuth(location)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("repository %s auth: %w", repository.URL, err)
 	}
 
-	var file *object.File
-	err = trees.ForEach(func(t *object.Tree) error {
-		file, err = t.File(fmt.Sprintf("snippets/%s.mk", name))
-		if errors.Is(err, object.ErrFileNotFound) {
-			return nil
-		}
-
-		if err == nil && file != nil {
-			return storer.ErrStop
-		}
-
-		return err
+	source, err := factory(FetchOptions{
+		URL:      url,
+		Auth:     auth,
+		Progress: progress,
+		Context:  ctx,
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return file, nil
+	repository.Source = source
+	return nil
+}
+
+// IsUnversioned returns true if the repository source has no versions, such
+// as a plain directory
+func (repository *Repository) IsUnversioned() bool {
+	_, unversioned := repository.Source.(UnversionedSource)
+
+	return unversioned
 }
 
 func (repository *Repository) HasSnippet(name string) bool {
@@ -116,16 +162,72 @@ func (repository *Repository) HasSnippet(name string) bool {
 	return exists
 }
 
-func (repository *Repository) AddSnippet(name, version string) error {
+func (repository *Repository) AddSnippet(name string, pin Pin) error {
 	if repository.HasSnippet(name) {
 		return fmt.Errorf("snippet %s already added", name)
 	}
 
-	repository.SetSnippet(name, version)
+	repository.SetSnippet(name, pin)
 
 	return nil
 }
 
-func (repository *Repository) SetSnippet(name, version string) {
-	repository.Snippets[name] = version
+func (repository *Repository) SetSnippet(name string, pin Pin) {
+	if repository.Snippets == nil {
+		repository.Snippets = make(map[string]Pin)
+	}
+
+	repository.Snippets[name] = pin
+}
+
+// httpClientKey is the context key of the client that fetches git repositories
+// over HTTP(S)
+type httpClientKey struct{}
+
+// withHTTPClient returns a context that fetches git repositories over HTTP(S)
+// with the client
+func withHTTPClient(ctx context.Context, httpClient *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientKey{}, httpClient)
+}
+
+var (
+	// gitHTTPTransport sends the git HTTP(S) requests with the client set on
+	// their context, or the default client otherwise
+	gitHTTPTransport = githttp.NewClient(&http.Client{Transport: contextClientTransport{}})
+	gitHTTPMutex     sync.Mutex
+)
+
+// UseGitHTTPClients makes git repositories fetch over HTTP(S) with the client
+// set by WithHTTPClient, which otherwise applies to archive and OCI sources
+// only. The go-git transports are not set per remote, so this replaces the
+// process-wide HTTP and HTTPS ones, and fails instead if the application
+// installed custom ones already
+func UseGitHTTPClients() error {
+	gitHTTPMutex.Lock()
+	defer gitHTTPMutex.Unlock()
+
+	for _, protocol := range []string{"http", "https"} {
+		installed := client.Protocols[protocol]
+		if installed != githttp.DefaultClient && installed != gitHTTPTransport {
+			return fmt.Errorf("custom git %s transport installed already", protocol)
+		}
+	}
+
+	client.InstallProtocol("http", gitHTTPTransport)
+	client.InstallProtocol("https", gitHTTPTransport)
+
+	return nil
+}
+
+// contextClientTransport sends requests with the client set on their context
+type contextClientTransport struct{}
+
+// RoundTrip implements http.RoundTripper
+func (contextClientTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	httpClient, _ := request.Context().Value(httpClientKey{}).(*http.Client)
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return httpClient.Do(request)
 }
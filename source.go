@@ -0,0 +1,165 @@
+package maker
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Source provides the snippets of a repository, at immutable revisions that
+// are recorded on the lock
+type Source interface {
+	// Versions returns the names of the snippet revisions that may be
+	// versions, such as git tags and branches
+	Versions(snippet string) ([]string, error)
+	// Resolve returns the immutable ID that a snippet reference currently
+	// refers to. The kind is PinVersion for names returned by Versions, or the
+	// explicit PinBranch, PinTag or PinCommit
+	Resolve(snippet string, kind PinKind, reference string) (string, error)
+	// Get returns the snippet contents at an immutable ID
+	Get(id, name string) ([]byte, error)
+	// Snippets returns the names of the snippets available at an immutable ID,
+	// sorted
+	Snippets(id string) ([]string, error)
+}
+
+// UnversionedSource is a Source without versions, such as a plain directory,
+// whose snippets are pinned with * to its current contents
+type UnversionedSource interface {
+	Source
+	// Current returns the immutable ID of the current contents
+	Current() (string, error)
+}
+
+// DatedSource is a Source whose immutable IDs are commits with a time, so its
+// untagged revisions are identified by Go module pseudo-versions
+type DatedSource interface {
+	Source
+	// Head returns the immutable ID of the default branch head
+	Head() (string, error)
+	// CommitTime returns the time an immutable ID was committed at
+	CommitTime(id string) (time.Time, error)
+}
+
+// source kinds, set on repository URLs with a kind+ prefix, e.g.
+// tar+https://example.com/snippets or dir+../snippets
+const (
+	sourceGit       = "git"
+	sourceDirectory = "dir"
+	sourceTar       = "tar"
+	sourceZip       = "zip"
+	sourceOCI       = "oci"
+)
+
+// OpenSource is the default RepositoryFactory. It selects the source by the
+// repository URL prefix: dir+ for plain directories, tar+ or zip+ for HTTP
+// release archives, and oci:// for OCI registry namespaces, or oci+http:// if
+// served over plain HTTP. Other URLs are git repositories, whose references are
+// listed without cloning them, and whose commits are fetched without history
+// when read, unless kept on the cache directory. Local paths and file:// URLs
+// are opened in place, either as git repositories or as plain directories
+func OpenSource(options FetchOptions) (Source, error) {
+	kind, url := splitSourceURL(options.URL)
+	options.URL = url
+
+	switch kind {
+	case sourceDirectory:
+		dir, local := localRepositoryPath(url)
+		if !local {
+			dir = url
+		}
+
+		return newDirectorySource(dir)
+	case sourceTar, sourceZip:
+		return newArchiveSource(kind, options)
+	case sourceOCI:
+		return newOCISource(options)
+	case sourceGit, "":
+		if dir, local := localRepositoryPath(url); local {
+			return openLocalRepository(dir, kind == sourceGit)
+		}
+
+		// cached repositories are fetched incrementally, so a full clone
+		// only costs once
+		if options.CacheDirectory != "" {
+			repository, err := CloneRepository(options)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewGitSource(repository), nil
+		}
+
+		return newRemoteGitSource(options)
+	default:
+		return nil, fmt.Errorf("unknown source kind %s", kind)
+	}
+}
+
+// splitSourceURL returns the source kind prefix of a repository URL, if any,
+// and the URL without it. OCI URLs are returned as HTTPS ones
+func splitSourceURL(url string) (string, string) {
+	if rest := strings.TrimPrefix(url, "oci://"); rest != url {
+		return sourceOCI, "https://" + rest
+	}
+
+	kind, rest, found := strings.Cut(url, "+")
+	if !found {
+		return "", url
+	}
+
+	switch kind {
+	case sourceGit, sourceDirectory, sourceTar, sourceZip, sourceOCI:
+		return kind, rest
+	default:
+		return "", url
+	}
+}
+
+// joinSourceURL returns the repository URL with the source kind prefix
+func joinSourceURL(kind, url string) string {
+	if kind == "" {
+		return url
+	}
+
+	return kind + "+" + url
+}
+
+// absoluteSourceURL bases a relative local repository path on the directory
+func absoluteSourceURL(url, dir string) string {
+	kind, rest := splitSourceURL(url)
+
+	path, local := localRepositoryPath(rest)
+	if !local && kind == sourceDirectory {
+		path, local = rest, true
+	}
+
+	if !local || filepath.IsAbs(path) {
+		return url
+	}
+
+	return joinSourceURL(kind, filepath.Join(dir, path))
+}
+
+// isLocalSource returns true if the repository URL is a local path, which needs
+// no network access
+func isLocalSource(url string) bool {
+	kind, rest := splitSourceURL(url)
+	_, local := localRepositoryPath(rest)
+
+	return local || kind == sourceDirectory
+}
+
+// sourceSnippetPath returns the snippet file path within a source
+func sourceSnippetPath(name string) string {
+	return fmt.Sprintf("snippets/%s.mk", name)
+}
+
+// blobHash returns the git blob hash of the data, which identifies snippet
+// contents on the lock regardless of their source
+func blobHash(data []byte) string {
+	return plumbing.ComputeHash(plumbing.BlobObject, data).String()
+}
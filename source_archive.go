@@ -0,0 +1,375 @@
+package maker
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// archiveSource provides snippets from release archives served over HTTP(S).
+// The base URL serves a versions file listing one version per line, and an
+// archive per version named after it, e.g. 1.2.0.tar.gz or 1.2.0.zip. Archives
+// have the snippets/*.mk files either at their root or within a single top
+// directory. Revisions are identified by the version and archive digest, so
+// archives replaced after being locked are rejected
+type archiveSource struct {
+	ctx    context.Context
+	url    string
+	format string
+	client *http.Client
+	auth   *githttp.BasicAuth
+
+	mutex sync.Mutex
+	// archives caches the files of each fetched version
+	archives map[string]*archive
+}
+
+type archive struct {
+	digest string
+	files  map[string][]byte
+}
+
+// VersionsFilename is the file that lists the versions of an archive source,
+// one per line
+const VersionsFilename = "versions"
+
+const (
+	// maxArchiveSize bounds the downloaded files and the total size of the
+	// archive contents, so broken or malicious servers cannot exhaust memory
+	maxArchiveSize = 64 << 20
+	// maxArchiveFileSize bounds the size of each archive member
+	maxArchiveFileSize = 8 << 20
+)
+
+func newArchiveSource(format string, options FetchOptions) (*archiveSource, error) {
+	source := &archiveSource{
+		ctx:      options.ctx(),
+		url:      strings.TrimSuffix(options.URL, "/"),
+		format:   format,
+		client:   options.HTTPClient,
+		archives: make(map[string]*archive),
+	}
+
+	if source.client == nil {
+		source.client = http.DefaultClient
+	}
+
+	if options.Auth != nil {
+		basicAuth, ok := options.Auth.(*githttp.BasicAuth)
+		if !ok {
+			return nil, fmt.Errorf("%s archives only support HTTP basic and token auth", source.url)
+		}
+
+		source.auth = basicAuth
+	}
+
+	return source, nil
+}
+
+// Versions implements Source
+func (source *archiveSource) Versions(snippet string) ([]string, error) {
+	data, err := source.fetch(VersionsFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		versions = append(versions, line)
+	}
+
+	return versions, scanner.Err()
+}
+
+// Resolve implements Source. Versions and tags are the same on archives
+func (source *archiveSource) Resolve(snippet string, kind PinKind, reference string) (string, error) {
+	if kind != PinVersion && kind != PinTag {
+		return "", fmt.Errorf("%s archives have no %s references", source.url, kind)
+	}
+
+	release, err := source.archive(reference)
+	if err != nil {
+		return "", err
+	}
+
+	return reference + "@" + release.digest, nil
+}
+
+// Get implements Source
+func (source *archiveSource) Get(id, name string) ([]byte, error) {
+	release, err := source.archiveByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, exists := release.files[sourceSnippetPath(name)]
+	if !exists {
+		return nil, fmt.Errorf("snippet %s not found at %s", name, id)
+	}
+
+	return data, nil
+}
+
+// Snippets implements Source
+func (source *archiveSource) Snippets(id string) ([]string, error) {
+	release, err := source.archiveByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(release.files))
+	for filename := range release.files {
+		names = append(names, strings.TrimSuffix(path.Base(filename), ".mk"))
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// archiveByID returns the archive of an ID, checking its digest
+func (source *archiveSource) archiveByID(id string) (*archive, error) {
+	version, digest, found := strings.Cut(id, "@")
+	if !found {
+		return nil, fmt.Errorf("invalid archive revision %s", id)
+	}
+
+	release, err := source.archive(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if release.digest != digest {
+		return nil, fmt.Errorf("archive %s changed from %s to %s", source.archiveURL(version), digest, release.digest)
+	}
+
+	return release, nil
+}
+
+// archive returns the snippet files of a version, fetching it if needed
+func (source *archiveSource) archive(version string) (*archive, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	release, exists := source.archives[version]
+	if exists {
+		return release, nil
+	}
+
+	data, err := source.fetch(source.archiveName(version))
+	if err != nil {
+		return nil, err
+	}
+
+	var files map[string][]byte
+
+	switch source.format {
+	case sourceTar:
+		files, err = readTarGz(data)
+	case sourceZip:
+		files, err = readZip(data)
+	default:
+		err = fmt.Errorf("unknown archive format %s", source.format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source.archiveURL(version), err)
+	}
+
+	release = &archive{
+		digest: fmt.Sprintf("sha256:%x", sha256.Sum256(data)),
+		files:  snippetFiles(files),
+	}
+
+	source.archives[version] = release
+
+	return release, nil
+}
+
+func (source *archiveSource) archiveName(version string) string {
+	if source.format == sourceTar {
+		return version + ".tar.gz"
+	}
+
+	return version + ".zip"
+}
+
+func (source *archiveSource) archiveURL(version string) string {
+	return source.url + "/" + source.archiveName(version)
+}
+
+// fetch returns the contents of a file relative to the source URL
+func (source *archiveSource) fetch(name string) ([]byte, error) {
+	url := source.url + "/" + name
+
+	request, err := http.NewRequestWithContext(source.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.auth != nil {
+		source.auth.SetAuth(request)
+	}
+
+	response, err := source.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, response.Status)
+	}
+
+	return readLimited(response.Body, url, maxArchiveSize)
+}
+
+// readLimited reads up to limit bytes, failing if there are more
+func readLimited(reader io.Reader, name string, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s exceeds %d bytes", name, limit)
+	}
+
+	return data, nil
+}
+
+// checkArchiveMember fails if an archive member name leaves the archive root,
+// or if its size exceeds what is left of the total archive size
+func checkArchiveMember(name string, size, left int64) error {
+	for _, segment := range strings.Split(strings.ReplaceAll(name, "\\", "/"), "/") {
+		if segment == ".." {
+			return fmt.Errorf("archive member %s is outside the archive", name)
+		}
+	}
+
+	if size > left {
+		return fmt.Errorf("archive contents exceed %d bytes", maxArchiveSize)
+	}
+
+	return nil
+}
+
+// readTarGz returns the regular files of a gzipped tarball
+func readTarGz(data []byte) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	files := make(map[string][]byte)
+	reader := tar.NewReader(gzipReader)
+	left := int64(maxArchiveSize)
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkArchiveMember(header.Name, header.Size, left)
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		files[header.Name], err = readLimited(reader, header.Name, maxArchiveFileSize)
+		if err != nil {
+			return nil, err
+		}
+
+		left -= int64(len(files[header.Name]))
+	}
+}
+
+// readZip returns the regular files of a zip archive
+func readZip(data []byte) (map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	left := int64(maxArchiveSize)
+
+	for _, file := range reader.File {
+		err = checkArchiveMember(file.Name, int64(file.UncompressedSize64), left)
+		if err != nil {
+			return nil, err
+		}
+
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		fd, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		files[file.Name], err = readLimited(fd, file.Name, maxArchiveFileSize)
+		fd.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		left -= int64(len(files[file.Name]))
+	}
+
+	return files, nil
+}
+
+// snippetFiles returns the snippets/*.mk archive files, keyed by their path
+// without the top directory that release archives usually have
+func snippetFiles(files map[string][]byte) map[string][]byte {
+	snippets := make(map[string][]byte)
+
+	for filename, data := range files {
+		filename = path.Clean(strings.TrimPrefix(filename, "./"))
+
+		if !strings.HasPrefix(filename, "snippets/") {
+			_, filename, _ = strings.Cut(filename, "/")
+		}
+
+		dir, name := path.Split(filename)
+		if dir != "snippets/" || path.Ext(name) != ".mk" {
+			continue
+		}
+
+		snippets[filename] = data
+	}
+
+	return snippets
+}
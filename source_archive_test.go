@@ -0,0 +1,243 @@
+package maker_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+func tarGzArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(gzipWriter)
+
+	for name, contents := range files {
+		err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = writer.Write([]byte(contents))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+
+	writer := zip.NewWriter(&buffer)
+
+	for name, contents := range files {
+		fd, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = fd.Write([]byte(contents))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// archiveServer serves the files by their path
+func archiveServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, exists := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestArchiveSource(t *testing.T) {
+	files := map[string][]byte{
+		"releases/versions": []byte("1.0.0\n1.1.0\n2.0.0\n"),
+		"releases/1.0.0.tar.gz": tarGzArchive(t, map[string]string{
+			"snippets-1.0.0/snippets/go.mk": "one\n",
+		}),
+		"releases/1.1.0.tar.gz": tarGzArchive(t, map[string]string{
+			"snippets-1.1.0/snippets/go.mk":   "one dot one\n",
+			"snippets-1.1.0/snippets/node.mk": "node\n",
+			"snippets-1.1.0/README.md":        "ignored\n",
+		}),
+		"releases/2.0.0.tar.gz": tarGzArchive(t, map[string]string{
+			"snippets/go.mk": "two\n",
+		}),
+	}
+
+	server := archiveServer(t, files)
+
+	conf := maker.NewMemoryFile([]byte(localConfig("tar+" + server.URL + "/releases")))
+	lock := maker.NewMemoryFile(nil)
+	directory := memfs.New()
+
+	mk, err := maker.New(conf, lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("local:go@^1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one dot one\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "one dot one\n")
+	}
+
+	if !bytes.Contains(lock.Bytes(), []byte("commit: 1.1.0@sha256:")) {
+		t.Errorf("lock does not contain the archive digest:\n%s", lock.Bytes())
+	}
+
+	err = mk.Add("local:missing@^1")
+	if err == nil || !strings.Contains(err.Error(), "go, node") {
+		t.Errorf("got error %v, want the available snippets listed", err)
+	}
+
+	// archives must not change once locked
+	files["releases/1.1.0.tar.gz"] = tarGzArchive(t, map[string]string{
+		"snippets/go.mk": "tampered\n",
+	})
+
+	mk, err = maker.New(conf, lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.InstallFrozen(maker.StrategyTheirs)
+	if err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("got error %v, want changed archive error", err)
+	}
+}
+
+func TestArchiveSourceZip(t *testing.T) {
+	server := archiveServer(t, map[string][]byte{
+		"versions": []byte("# released versions\n1.0.0\n"),
+		"1.0.0.zip": zipArchive(t, map[string]string{
+			"snippets/go.mk": "zipped\n",
+		}),
+	})
+
+	directory := memfs.New()
+
+	mk, err := maker.New(maker.NewMemoryFile([]byte(localConfig("zip+"+server.URL))), maker.NewMemoryFile(nil), directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("local:go@branch:main")
+	if err == nil {
+		t.Error("got no error pinning an archive snippet to a branch")
+	}
+
+	err = mk.Add("local:go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "zipped\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "zipped\n")
+	}
+}
+
+func TestArchiveSourceUnsafe(t *testing.T) {
+	tests := map[string][]byte{
+		"tar+": tarGzArchive(t, map[string]string{
+			"snippets/../../go.mk": "outside\n",
+		}),
+		"zip+": zipArchive(t, map[string]string{
+			"../snippets/go.mk": "outside\n",
+		}),
+	}
+
+	for scheme, archive := range tests {
+		name := "1.0.0.zip"
+		if scheme == "tar+" {
+			name = "1.0.0.tar.gz"
+		}
+
+		server := archiveServer(t, map[string][]byte{
+			"versions": []byte("1.0.0\n"),
+			name:       archive,
+		})
+
+		mk, err := maker.New(maker.NewMemoryFile([]byte(localConfig(scheme+server.URL))), maker.NewMemoryFile(nil), memfs.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mk.Add("local:go")
+		if err == nil || !strings.Contains(err.Error(), "outside the archive") {
+			t.Errorf("%s: got error %v, want member outside the archive error", scheme, err)
+		}
+	}
+
+	// members are read up to a size limit, whatever their compressed size
+	server := archiveServer(t, map[string][]byte{
+		"versions": []byte("1.0.0\n"),
+		"1.0.0.tar.gz": tarGzArchive(t, map[string]string{
+			"snippets/go.mk": strings.Repeat("\n", 8<<20+1),
+		}),
+	})
+
+	mk, err := maker.New(maker.NewMemoryFile([]byte(localConfig("tar+"+server.URL))), maker.NewMemoryFile(nil), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("local:go")
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("got error %v, want oversized member error", err)
+	}
+}
@@ -0,0 +1,171 @@
+package maker
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+// localRepositoryPath returns the filesystem path of a local repository URL,
+// which is either a file:// URL, an absolute path or a path relative to the
+// current directory starting with ./ or ../
+func localRepositoryPath(repositoryURL string) (string, bool) {
+	if strings.HasPrefix(repositoryURL, "file://") {
+		parsed, err := url.Parse(repositoryURL)
+		if err != nil {
+			return "", false
+		}
+
+		return filepath.FromSlash(parsed.Path), true
+	}
+
+	if filepath.IsAbs(repositoryURL) || repositoryURL == "." || repositoryURL == ".." {
+		return repositoryURL, true
+	}
+
+	for _, prefix := range []string{"./", "../", `.\`, `..\`} {
+		if strings.HasPrefix(repositoryURL, prefix) {
+			return repositoryURL, true
+		}
+	}
+
+	return "", false
+}
+
+// openLocalRepository opens a git repository from the filesystem in place, or
+// a plain directory of snippets/*.mk files if allowed
+func openLocalRepository(dir string, gitOnly bool) (Source, error) {
+	repository, err := git.PlainOpen(dir)
+	if err == nil {
+		return NewGitSource(repository), nil
+	}
+
+	if gitOnly || !errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "snippets"))
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is neither a git repository nor a snippets directory", dir)
+	}
+
+	return newDirectorySource(dir)
+}
+
+// directorySource provides the snippets of a plain directory, as a working
+// tree without versions. Its only revision is the current contents, identified
+// by their digest
+type directorySource struct {
+	dir string
+}
+
+func newDirectorySource(dir string) (*directorySource, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return &directorySource{dir: dir}, nil
+}
+
+// Versions implements Source
+func (source *directorySource) Versions(snippet string) ([]string, error) {
+	return []string{}, nil
+}
+
+// Resolve implements Source
+func (source *directorySource) Resolve(snippet string, kind PinKind, reference string) (string, error) {
+	return "", fmt.Errorf("%s is a plain directory without versions", source.dir)
+}
+
+// Current implements UnversionedSource. The digest covers the names and blob
+// hashes of all snippets
+func (source *directorySource) Current() (string, error) {
+	names, err := source.names()
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.New()
+
+	for _, name := range names {
+		data, err := source.read(name)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(digest, "%s %s\n", blobHash(data), name)
+	}
+
+	return fmt.Sprintf("sha256:%x", digest.Sum(nil)), nil
+}
+
+// Get implements Source
+func (source *directorySource) Get(id, name string) ([]byte, error) {
+	err := source.checkCurrent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := source.read(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("snippet %s not found on %s", name, source.dir)
+	}
+
+	return data, err
+}
+
+// Snippets implements Source
+func (source *directorySource) Snippets(id string) ([]string, error) {
+	err := source.checkCurrent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return source.names()
+}
+
+// checkCurrent fails if the directory contents changed since the ID
+func (source *directorySource) checkCurrent(id string) error {
+	current, err := source.Current()
+	if err != nil {
+		return err
+	}
+
+	if id != current {
+		return fmt.Errorf("%s changed since %s", source.dir, id)
+	}
+
+	return nil
+}
+
+func (source *directorySource) names() ([]string, error) {
+	filenames, err := filepath.Glob(filepath.Join(source.dir, "snippets", "*.mk"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(filenames))
+	for _, filename := range filenames {
+		names = append(names, strings.TrimSuffix(filepath.Base(filename), ".mk"))
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (source *directorySource) read(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(source.dir, filepath.FromSlash(sourceSnippetPath(name))))
+}
@@ -0,0 +1,333 @@
+package maker
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// CloneRepository clones a remote git repository into memory, or into the
+// cache directory if set, fetching only the new references on later calls
+func CloneRepository(options FetchOptions) (*git.Repository, error) {
+	if options.CacheDirectory != "" {
+		return cachedRepository(options)
+	}
+
+	return git.CloneContext(options.ctx(), memory.NewStorage(), nil, &git.CloneOptions{
+		URL:      options.URL,
+		Auth:     options.Auth,
+		Progress: options.Progress,
+	})
+}
+
+// cachedRepository clones the repository as a bare one on the cache directory,
+// or fetches it if cloned already. Other processes using the same cache wait
+// for the fetch to finish
+func cachedRepository(options FetchOptions) (*git.Repository, error) {
+	err := os.MkdirAll(options.CacheDirectory, 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(options.CacheDirectory, fmt.Sprintf("%x", sha256.Sum256([]byte(options.URL))))
+
+	processLock, err := AcquireProcessLock(dir+".lock", DefaultLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer processLock.Close()
+
+	repository, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repository, err = git.PlainCloneContext(options.ctx(), dir, true, &git.CloneOptions{
+			URL:      options.URL,
+			Auth:     options.Auth,
+			Progress: options.Progress,
+		})
+		if err != nil {
+			// partial clones would be taken as complete on the next call
+			os.RemoveAll(dir)
+		}
+
+		return repository, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = repository.FetchContext(options.ctx(), &git.FetchOptions{
+		Auth:     options.Auth,
+		Progress: options.Progress,
+		Tags:     git.AllTags,
+		Force:    true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, err
+	}
+
+	return repository, nil
+}
+
+// gitSource provides snippets from a git repository, identifying revisions by
+// their commit hashes
+type gitSource struct {
+	repository *git.Repository
+	// mutex serializes the storage access, which is not concurrency-safe
+	mutex sync.Mutex
+}
+
+// NewGitSource returns a Source backed by a git repository, with its tags and
+// branches as versions
+func NewGitSource(repository *git.Repository) Source {
+	return &gitSource{repository: repository}
+}
+
+// Versions implements Source
+func (source *gitSource) Versions(snippet string) ([]string, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	refs, err := source.repository.References()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0)
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		if name.IsBranch() || name.IsTag() {
+			versions = append(versions, name.Short())
+		}
+
+		return nil
+	})
+
+	sort.Strings(versions)
+
+	return versions, err
+}
+
+// Resolve implements Source
+func (source *gitSource) Resolve(snippet string, kind PinKind, reference string) (string, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	var (
+		hash *plumbing.Hash
+		err  error
+	)
+
+	switch kind {
+	case PinVersion:
+		hash, err = source.repository.ResolveRevision(plumbing.Revision(reference))
+	case PinBranch:
+		hash, err = source.resolveBranch(reference)
+	case PinTag:
+		hash, err = source.repository.ResolveRevision(plumbing.Revision(plumbing.NewTagReferenceName(reference)))
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			err = fmt.Errorf("tag %s not found", reference)
+		}
+	case PinCommit:
+		hash, err = source.resolveCommit(reference)
+	default:
+		err = fmt.Errorf("unknown pin kind %s", kind)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+// resolveCommit returns the hash of the commit with the hash prefix, which is
+// ambiguous if several commits have it
+func (source *gitSource) resolveCommit(prefix string) (*plumbing.Hash, error) {
+	prefix = strings.ToLower(prefix)
+
+	// full hashes need no scan
+	if hash := plumbing.NewHash(prefix); hash.String() == prefix {
+		_, err := source.repository.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil, fmt.Errorf("commit %s not found", prefix)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &hash, nil
+	}
+
+	commits, err := source.repository.CommitObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []plumbing.Hash
+
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if strings.HasPrefix(commit.Hash.String(), prefix) {
+			matches = append(matches, commit.Hash)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("commit %s not found", prefix)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("ambiguous commit prefix %s", prefix)
+	}
+}
+
+// resolveBranch returns the head commit hash of a branch, preferring the
+// remote one
+func (source *gitSource) resolveBranch(branch string) (*plumbing.Hash, error) {
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch),
+		plumbing.NewBranchReferenceName(branch),
+	} {
+		ref, err := source.repository.Reference(name, true)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		hash := ref.Hash()
+		return &hash, nil
+	}
+
+	return nil, fmt.Errorf("branch %s not found", branch)
+}
+
+// Head implements DatedSource
+func (source *gitSource) Head() (string, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	ref, err := source.repository.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return ref.Hash().String(), nil
+}
+
+// CommitTime implements DatedSource
+func (source *gitSource) CommitTime(id string) (time.Time, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	commit, err := source.commit(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return commit.Committer.When, nil
+}
+
+// Get implements Source
+func (source *gitSource) Get(id, name string) ([]byte, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	commit, err := source.commit(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := commit.File(sourceSnippetPath(name))
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, fmt.Errorf("snippet %s not found at %s", name, id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// Snippets implements Source
+func (source *gitSource) Snippets(id string) ([]string, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	commit, err := source.commit(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	snippetsTree, err := tree.Tree("snippets")
+	if errors.Is(err, object.ErrDirectoryNotFound) {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(snippetsTree.Entries))
+	for _, entry := range snippetsTree.Entries {
+		if entry.Mode == filemode.Dir || path.Ext(entry.Name) != ".mk" {
+			continue
+		}
+
+		names = append(names, strings.TrimSuffix(entry.Name, ".mk"))
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// commit returns the commit of a revision
+func (source *gitSource) commit(revision string) (*object.Commit, error) {
+	hash, err := source.repository.ResolveRevision(plumbing.Revision(revision))
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("revision %s not found", revision)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return source.repository.CommitObject(*hash)
+}
@@ -0,0 +1,281 @@
+package maker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// remoteGitSource provides snippets from a remote git repository without
+// cloning it. References are listed from the remote advertisement, and only
+// the commits snippets are read from are fetched, without their history.
+// Commits that no reference points to, and commit prefixes, which may match
+// any object, require the full history, so the repository is cloned on their
+// first use
+type remoteGitSource struct {
+	options FetchOptions
+	// refs maps the tag and branch names to their commit hashes, with
+	// annotated tags peeled, and HEAD to the default branch head
+	refs map[plumbing.ReferenceName]plumbing.Hash
+
+	// mutex guards the commits map only, so fetches run without holding it
+	mutex sync.Mutex
+	// commits holds the commits fetched on their own repository so far
+	commits map[plumbing.Hash]*remoteFetch
+	// full is the cloned repository, once used
+	full remoteFetch
+}
+
+// remoteFetch fetches a source once it succeeds, sharing it with concurrent
+// and later callers. Callers wait for fetches of the same source only
+type remoteFetch struct {
+	mutex  sync.Mutex
+	source Source
+}
+
+// get returns the fetched source, fetching it if no call succeeded yet
+func (fetch *remoteFetch) get(fetchSource func() (Source, error)) (Source, error) {
+	fetch.mutex.Lock()
+	defer fetch.mutex.Unlock()
+
+	if fetch.source != nil {
+		return fetch.source, nil
+	}
+
+	source, err := fetchSource()
+	if err != nil {
+		return nil, err
+	}
+
+	fetch.source = source
+
+	return source, nil
+}
+
+// newRemoteGitSource lists the remote references, which fails fast if the
+// remote is unreachable
+func newRemoteGitSource(options FetchOptions) (*remoteGitSource, error) {
+	endpoint, err := transport.NewEndpoint(options.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	transportClient, err := client.NewClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := transportClient.NewUploadPackSession(endpoint, options.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	advertised, err := session.AdvertisedReferencesContext(options.ctx())
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[plumbing.ReferenceName]plumbing.Hash, len(advertised.References))
+	for name, hash := range advertised.References {
+		if peeled, exists := advertised.Peeled[name]; exists {
+			hash = peeled
+		}
+
+		refs[plumbing.ReferenceName(name)] = hash
+	}
+
+	if advertised.Head != nil {
+		refs[plumbing.HEAD] = *advertised.Head
+	}
+
+	return &remoteGitSource{
+		options: options,
+		refs:    refs,
+		commits: make(map[plumbing.Hash]*remoteFetch),
+	}, nil
+}
+
+// Versions implements Source
+func (source *remoteGitSource) Versions(snippet string) ([]string, error) {
+	versions := make([]string, 0, len(source.refs))
+
+	for name := range source.refs {
+		if name.IsBranch() || name.IsTag() {
+			versions = append(versions, name.Short())
+		}
+	}
+
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+// Resolve implements Source. Version names are tags or branches, like git
+// revisions, and commits are resolved without fetching only if a reference
+// points to them. Commit prefixes are resolved on the cloned repository, as
+// they are ambiguous if they match any other object
+func (source *remoteGitSource) Resolve(snippet string, kind PinKind, reference string) (string, error) {
+	var candidates []plumbing.ReferenceName
+
+	switch kind {
+	case PinVersion:
+		candidates = []plumbing.ReferenceName{
+			plumbing.NewTagReferenceName(reference),
+			plumbing.NewBranchReferenceName(reference),
+		}
+	case PinBranch:
+		candidates = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(reference)}
+	case PinTag:
+		candidates = []plumbing.ReferenceName{plumbing.NewTagReferenceName(reference)}
+	case PinCommit:
+		hash := plumbing.NewHash(reference)
+		if hash.String() == strings.ToLower(reference) {
+			for _, refHash := range source.refs {
+				if refHash == hash {
+					return hash.String(), nil
+				}
+			}
+		}
+
+		full, err := source.fullSource()
+		if err != nil {
+			return "", err
+		}
+
+		return full.Resolve(snippet, kind, reference)
+	default:
+		return "", fmt.Errorf("unknown pin kind %s", kind)
+	}
+
+	for _, name := range candidates {
+		if hash, exists := source.refs[name]; exists {
+			return hash.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("%s %s not found", kind, reference)
+}
+
+// Head implements DatedSource
+func (source *remoteGitSource) Head() (string, error) {
+	hash, exists := source.refs[plumbing.HEAD]
+	if !exists {
+		return "", fmt.Errorf("%s has no default branch", source.options.URL)
+	}
+
+	return hash.String(), nil
+}
+
+// CommitTime implements DatedSource, fetching the commit if needed
+func (source *remoteGitSource) CommitTime(id string) (time.Time, error) {
+	commitSource, err := source.commitSource(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return commitSource.(DatedSource).CommitTime(id)
+}
+
+// Get implements Source
+func (source *remoteGitSource) Get(id, name string) ([]byte, error) {
+	commitSource, err := source.commitSource(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return commitSource.Get(id, name)
+}
+
+// Snippets implements Source
+func (source *remoteGitSource) Snippets(id string) ([]string, error) {
+	commitSource, err := source.commitSource(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return commitSource.Snippets(id)
+}
+
+// commitSource returns a source with the commit available, fetching just the
+// commit of a reference that points to it, or cloning the whole repository.
+// Each commit is fetched once, on a repository of its own
+func (source *remoteGitSource) commitSource(id string) (Source, error) {
+	hash := plumbing.NewHash(id)
+
+	var refName plumbing.ReferenceName
+	for name, refHash := range source.refs {
+		if refHash == hash && (name.IsBranch() || name.IsTag()) {
+			refName = name
+			break
+		}
+	}
+
+	if refName == "" {
+		return source.fullSource()
+	}
+
+	source.mutex.Lock()
+	fetch, exists := source.commits[hash]
+	if !exists {
+		fetch = &remoteFetch{}
+		source.commits[hash] = fetch
+	}
+	source.mutex.Unlock()
+
+	return fetch.get(func() (Source, error) {
+		return source.fetchCommit(refName)
+	})
+}
+
+// fetchCommit fetches just the commit a reference points to, without its
+// history, into a new in-memory repository
+func (source *remoteGitSource) fetchCommit(name plumbing.ReferenceName) (Source, error) {
+	repository, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repository.CreateRemote(&config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{source.options.URL},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = repository.FetchContext(source.options.ctx(), &git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", name, name))},
+		Depth:    1,
+		Tags:     git.NoTags,
+		Auth:     source.options.Auth,
+		Progress: source.options.Progress,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, err
+	}
+
+	return NewGitSource(repository), nil
+}
+
+// fullSource returns the source of the cloned repository, cloning it if needed
+func (source *remoteGitSource) fullSource() (Source, error) {
+	return source.full.get(func() (Source, error) {
+		repository, err := CloneRepository(source.options)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewGitSource(repository), nil
+	})
+}
@@ -0,0 +1,369 @@
+package maker_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/wwmoraes/maker"
+)
+
+// upstreamRepository commits the go snippet contents to an on-disk repository,
+// tagging the commit if the tag is set, as an annotated tag if requested
+type upstreamRepository func(contents, tag string, annotated bool) plumbing.Hash
+
+func newUpstreamRepository(t *testing.T, dir string) upstreamRepository {
+	t.Helper()
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+
+	return func(contents, tag string, annotated bool) plumbing.Hash {
+		t.Helper()
+
+		writeTestFile(t, filepath.Join(dir, "snippets", "go.mk"), contents)
+
+		_, err := worktree.Add("snippets/go.mk")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := worktree.Commit(contents, &git.CommitOptions{Author: signature})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tag == "" {
+			return hash
+		}
+
+		var options *git.CreateTagOptions
+		if annotated {
+			options = &git.CreateTagOptions{Tagger: signature, Message: tag}
+		}
+
+		_, err = repo.CreateTag(tag, hash, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return hash
+	}
+}
+
+func TestCloneRepositoryCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := t.TempDir()
+
+	commit := newUpstreamRepository(t, dir)
+	commit("1.0.0\n", "1.0.0", false)
+
+	options := maker.FetchOptions{URL: dir, CacheDirectory: cache}
+
+	_, err := maker.CloneRepository(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit("1.1.0\n", "1.1.0", false)
+
+	repository, err := maker.CloneRepository(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repository.Tag("1.1.0")
+	if err != nil {
+		t.Errorf("cached repository was not fetched again: %v", err)
+	}
+
+	entries, err := os.ReadDir(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the repository and its lock file
+	if len(entries) != 2 {
+		t.Errorf("got %d cache entries, want 2", len(entries))
+	}
+}
+
+func TestRemoteGitRepository(t *testing.T) {
+	backendPath := gitHTTPBackend(t)
+
+	root := t.TempDir()
+
+	commit := newUpstreamRepository(t, filepath.Join(root, "snippets"))
+	commit("one\n", "1.0.0", true)
+	untagged := commit("untagged\n", "", false)
+	commit("one dot one\n", "1.1.0", false)
+
+	backend := &cgi.Handler{
+		Path: backendPath,
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	}
+
+	// counts the fetches, as listing references is a GET request
+	var fetches int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			fetches++
+		}
+
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	conf := maker.NewMemoryFile([]byte(localConfig(server.URL + "/snippets/.git")))
+	lock := maker.NewMemoryFile(nil)
+	directory := memfs.New()
+
+	mk, err := maker.New(conf, lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outdated, err := mk.Outdated()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outdated) != 0 || fetches != 0 {
+		t.Errorf("got outdated snippets %+v after %d fetches, want none", outdated, fetches)
+	}
+
+	err = mk.Add("local:go@1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one\n" {
+		t.Errorf("got snippet contents %q of the annotated tag, want %q", data, "one\n")
+	}
+
+	if fetches != 1 {
+		t.Errorf("got %d fetches adding a tagged snippet, want 1", fetches)
+	}
+
+	err = mk.Remove("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("local:go@commit:" + untagged.String()[:10])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "untagged\n" {
+		t.Errorf("got snippet contents %q of the untagged commit, want %q", data, "untagged\n")
+	}
+
+	if !strings.Contains(string(lock.Bytes()), "commit: "+untagged.String()) {
+		t.Errorf("lock does not contain the untagged commit:\n%s", lock.Bytes())
+	}
+}
+
+// countingTransport counts the requests sent through it
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (transport *countingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.requests.Add(1)
+
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func TestHTTPClientPerInstance(t *testing.T) {
+	backendPath := gitHTTPBackend(t)
+
+	root := t.TempDir()
+	newUpstreamRepository(t, filepath.Join(root, "snippets"))("one\n", "1.0.0", false)
+
+	server := httptest.NewServer(&cgi.Handler{
+		Path: backendPath,
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(server.Close)
+
+	err := maker.UseGitHTTPClients()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := []byte(localConfig(server.URL + "/snippets/.git"))
+	transports := []*countingTransport{{}, {}}
+	instances := make([]*maker.Maker, 0, len(transports))
+
+	// all instances are created before any fetches, so none overrides another
+	for _, transport := range transports {
+		mk, err := maker.New(maker.NewMemoryFile(config), maker.NewMemoryFile(nil), memfs.New(), maker.WithHTTPClient(&http.Client{Transport: transport}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		instances = append(instances, mk)
+	}
+
+	for index, mk := range instances {
+		before := transports[1-index].requests.Load()
+
+		err := mk.Add("local:go@1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if transports[index].requests.Load() == 0 {
+			t.Errorf("got no requests through the client of instance %d", index)
+		}
+
+		if transports[1-index].requests.Load() != before {
+			t.Errorf("got requests of instance %d through the client of instance %d", index, 1-index)
+		}
+	}
+
+	// instances without a client keep using the default one
+	before := transports[0].requests.Load() + transports[1].requests.Load()
+
+	mk, err := maker.New(maker.NewMemoryFile(config), maker.NewMemoryFile(nil), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("local:go@1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after := transports[0].requests.Load() + transports[1].requests.Load(); after != before {
+		t.Errorf("got %d requests of an instance without a client through the others", after-before)
+	}
+}
+
+func TestUseGitHTTPClientsCustomTransport(t *testing.T) {
+	installed := client.Protocols["https"]
+	t.Cleanup(func() { client.InstallProtocol("https", installed) })
+
+	custom := githttp.NewClient(&http.Client{})
+	client.InstallProtocol("https", custom)
+
+	err := maker.UseGitHTTPClients()
+	if err == nil {
+		t.Error("got no error with a custom transport installed")
+	}
+
+	if client.Protocols["https"] != custom {
+		t.Error("got the custom transport replaced")
+	}
+}
+
+func TestRemoteGitAmbiguousCommit(t *testing.T) {
+	backendPath := gitHTTPBackend(t)
+
+	root := t.TempDir()
+	commit := newUpstreamRepository(t, filepath.Join(root, "snippets"))
+
+	// two of any 17 commits share the first hash digit, and only the last one
+	// has a reference, so the others are only known to the full history
+	byDigit := make(map[byte]plumbing.Hash)
+
+	var prefix string
+
+	for index := 0; prefix == ""; index++ {
+		hash := commit(fmt.Sprintf("%d\n", index), "", false)
+
+		if _, exists := byDigit[hash.String()[0]]; exists {
+			prefix = hash.String()[:1]
+		}
+
+		byDigit[hash.String()[0]] = hash
+	}
+
+	server := httptest.NewServer(&cgi.Handler{
+		Path: backendPath,
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(server.Close)
+
+	mk, err := maker.New(maker.NewMemoryFile([]byte(localConfig(server.URL+"/snippets/.git"))), maker.NewMemoryFile(nil), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("local:go@commit:" + prefix)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous commit prefix "+prefix) {
+		t.Errorf("got error %v, want an ambiguous commit prefix", err)
+	}
+
+	err = mk.Add("local:go@commit:" + byDigit[prefix[0]].String()[:12])
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteGitVersionsSorted(t *testing.T) {
+	backendPath := gitHTTPBackend(t)
+
+	root := t.TempDir()
+	commit := newUpstreamRepository(t, filepath.Join(root, "snippets"))
+
+	for _, tag := range []string{"1.0.0", "0.1.0", "2.0.0", "1.1.0"} {
+		commit(tag+"\n", tag, false)
+	}
+
+	server := httptest.NewServer(&cgi.Handler{
+		Path: backendPath,
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(server.Close)
+
+	source, err := maker.OpenSource(maker.FetchOptions{URL: server.URL + "/snippets/.git"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := source.Versions("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !sort.StringsAreSorted(versions) || len(versions) != 5 {
+		t.Errorf("got versions %v, want the tags and branch sorted", versions)
+	}
+}
@@ -0,0 +1,647 @@
+package maker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// media types of the snippet OCI artifacts. Each snippet version is an
+// artifact tagged with the version on a repository named after the snippet,
+// e.g. registry.example.com/ns/snippets/golang:1.4.0, with the metadata as
+// config and the snippet file as its single layer
+const (
+	SnippetArtifactType    = "application/vnd.maker.snippet.v1"
+	SnippetConfigMediaType = "application/vnd.maker.snippet.config.v1+json"
+	SnippetLayerMediaType  = "application/vnd.maker.snippet.layer.v1.mk"
+
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociTitleAnnotation   = "org.opencontainers.image.title"
+)
+
+// SnippetMetadata is the config of snippet OCI artifacts
+type SnippetMetadata struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	ArtifactType  string          `json:"artifactType,omitempty"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociTags struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// ociSource provides snippets from OCI artifacts on a registry namespace.
+// Revisions are identified by the snippet name and manifest digest, e.g.
+// golang@sha256:abc123, which commit pins accept with or without the name
+type ociSource struct {
+	registry  *ociRegistry
+	namespace string
+}
+
+func newOCISource(options FetchOptions) (*ociSource, error) {
+	registry, namespace, err := newOCIRegistry(options.ctx(), options.URL, options.Auth, options.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ociSource{
+		registry:  registry,
+		namespace: strings.Trim(namespace, "/"),
+	}, nil
+}
+
+// Versions implements Source
+func (source *ociSource) Versions(snippet string) ([]string, error) {
+	return source.registry.tags(source.repository(snippet))
+}
+
+// Resolve implements Source. Versions and tags are the same on registries,
+// while commits are manifest digests, with or without the snippet name
+func (source *ociSource) Resolve(snippet string, kind PinKind, reference string) (string, error) {
+	switch kind {
+	case PinVersion, PinTag:
+	case PinCommit:
+		name, digest, found := strings.Cut(reference, "@")
+		if found && name != snippet {
+			return "", fmt.Errorf("commit %s is not a revision of snippet %s", reference, snippet)
+		}
+
+		if found {
+			reference = digest
+		}
+	default:
+		return "", fmt.Errorf("OCI registries have no %s references", kind)
+	}
+
+	_, digest, err := source.registry.manifest(source.repository(snippet), reference)
+	if err != nil {
+		return "", err
+	}
+
+	return snippet + "@" + digest, nil
+}
+
+// Get implements Source
+func (source *ociSource) Get(id, name string) ([]byte, error) {
+	snippet, manifest, err := source.manifest(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if snippet != name {
+		return nil, fmt.Errorf("snippet %s not found at %s", name, id)
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == SnippetLayerMediaType {
+			return source.registry.blob(source.repository(snippet), layer.Digest)
+		}
+	}
+
+	return nil, fmt.Errorf("%s has no %s layer", id, SnippetLayerMediaType)
+}
+
+// Snippets implements Source. Each artifact has a single snippet
+func (source *ociSource) Snippets(id string) ([]string, error) {
+	snippet, _, err := source.manifest(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{snippet}, nil
+}
+
+// manifest returns the snippet and snippet artifact manifest of an ID
+func (source *ociSource) manifest(id string) (string, *ociManifest, error) {
+	snippet, digest, found := strings.Cut(id, "@")
+	if !found {
+		return "", nil, fmt.Errorf("invalid OCI revision %s", id)
+	}
+
+	data, _, err := source.registry.manifest(source.repository(snippet), digest)
+	if err != nil {
+		return "", nil, err
+	}
+
+	manifest := &ociManifest{}
+
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", id, err)
+	}
+
+	if manifest.ArtifactType != SnippetArtifactType && manifest.Config.MediaType != SnippetConfigMediaType {
+		return "", nil, fmt.Errorf("%s is not a snippet artifact", id)
+	}
+
+	return snippet, manifest, nil
+}
+
+// repository returns the registry repository of a snippet
+func (source *ociSource) repository(snippet string) string {
+	return path.Join(source.namespace, snippet)
+}
+
+// PushOptions describes a snippet version to publish as an OCI artifact
+type PushOptions struct {
+	// Reference is the snippet artifact reference, e.g.
+	// registry.example.com/ns/snippets/golang:1.4.0, optionally prefixed by
+	// oci:// or, for plain HTTP registries, oci+http://
+	Reference   string
+	Contents    []byte
+	Description string
+	// Auth sets how to authenticate against the registry, which defaults to
+	// the netrc file entry of its host, if any
+	Auth *Auth
+	// HTTPClient sends the registry requests, if not nil
+	HTTPClient *http.Client
+}
+
+// PushSnippet publishes a snippet version as an OCI artifact, and returns its
+// manifest digest
+func PushSnippet(options PushOptions) (string, error) {
+	return PushSnippetContext(context.Background(), options)
+}
+
+// PushSnippetContext publishes a snippet version like PushSnippet, stopping if
+// the context is done
+func PushSnippetContext(ctx context.Context, options PushOptions) (string, error) {
+	reference := options.Reference
+	if _, rest := splitSourceURL(reference); rest == reference {
+		reference = "oci://" + reference
+	}
+
+	kind, registryURL := splitSourceURL(reference)
+	if kind != sourceOCI {
+		return "", fmt.Errorf("%s is not an OCI reference", options.Reference)
+	}
+
+	colon := strings.LastIndex(registryURL, ":")
+	if colon < 0 || strings.Contains(registryURL[colon:], "/") {
+		return "", fmt.Errorf("%s has no version tag", options.Reference)
+	}
+
+	auth, err := options.Auth.TransportAuth(registryURL[:colon])
+	if err != nil {
+		return "", err
+	}
+
+	registry, repository, err := newOCIRegistry(ctx, registryURL[:colon], auth, options.HTTPClient)
+	if err != nil {
+		return "", err
+	}
+
+	repository = strings.Trim(repository, "/")
+	version := registryURL[colon+1:]
+
+	config, err := json.Marshal(&SnippetMetadata{
+		Name:        path.Base(repository),
+		Version:     version,
+		Description: options.Description,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	configDescriptor, err := registry.pushBlob(repository, SnippetConfigMediaType, config)
+	if err != nil {
+		return "", err
+	}
+
+	layerDescriptor, err := registry.pushBlob(repository, SnippetLayerMediaType, options.Contents)
+	if err != nil {
+		return "", err
+	}
+
+	layerDescriptor.Annotations = map[string]string{
+		ociTitleAnnotation: snippetFilename(path.Base(repository)),
+	}
+
+	manifest, err := json.Marshal(&ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  SnippetArtifactType,
+		Config:        *configDescriptor,
+		Layers:        []ociDescriptor{*layerDescriptor},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return registry.pushManifest(repository, version, manifest)
+}
+
+// ociRegistry is a client of the OCI distribution API, which authenticates
+// with either HTTP basic auth or bearer tokens issued by the registry
+type ociRegistry struct {
+	ctx    context.Context
+	base   *url.URL
+	client *http.Client
+	auth   *githttp.BasicAuth
+
+	mutex sync.Mutex
+	// token is the last bearer token issued by the registry
+	token string
+}
+
+// newOCIRegistry returns a registry client for the host of an URL, along with
+// the URL path
+func newOCIRegistry(ctx context.Context, registryURL string, auth transport.AuthMethod, client *http.Client) (*ociRegistry, string, error) {
+	parsed, err := url.Parse(registryURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if parsed.Host == "" {
+		return nil, "", fmt.Errorf("%s has no registry host", registryURL)
+	}
+
+	registry := &ociRegistry{
+		ctx:    ctx,
+		base:   &url.URL{Scheme: parsed.Scheme, Host: parsed.Host},
+		client: client,
+	}
+
+	if registry.client == nil {
+		registry.client = http.DefaultClient
+	}
+
+	if auth != nil {
+		basicAuth, ok := auth.(*githttp.BasicAuth)
+		if !ok {
+			return nil, "", fmt.Errorf("OCI registries only support HTTP basic and token auth")
+		}
+
+		registry.auth = basicAuth
+	}
+
+	return registry, parsed.Path, nil
+}
+
+// tags returns the tags of a repository
+func (registry *ociRegistry) tags(repository string) ([]string, error) {
+	response, err := registry.send(http.MethodGet, "/v2/"+repository+"/tags/list", nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("snippet repository %s not found", repository)
+	}
+
+	err = checkStatus(response, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := &ociTags{}
+
+	err = json.NewDecoder(response.Body).Decode(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags.Tags, nil
+}
+
+// manifest returns a repository manifest by tag or digest, along with its
+// digest, which is checked when fetched by digest
+func (registry *ociRegistry) manifest(repository, reference string) ([]byte, string, error) {
+	response, err := registry.send(http.MethodGet, "/v2/"+repository+"/manifests/"+reference, map[string]string{
+		"Accept": ociManifestMediaType,
+	}, "", nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, "", fmt.Errorf("%s:%s not found", repository, reference)
+	}
+
+	err = checkStatus(response, http.StatusOK)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	digest := ociDigest(data)
+	if strings.HasPrefix(reference, "sha256:") && digest != reference {
+		return nil, "", fmt.Errorf("manifest %s of %s has digest %s", reference, repository, digest)
+	}
+
+	return data, digest, nil
+}
+
+// blob returns a repository blob, checking its digest
+func (registry *ociRegistry) blob(repository, digest string) ([]byte, error) {
+	response, err := registry.send(http.MethodGet, "/v2/"+repository+"/blobs/"+digest, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	err = checkStatus(response, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if ociDigest(data) != digest {
+		return nil, fmt.Errorf("blob %s of %s has digest %s", digest, repository, ociDigest(data))
+	}
+
+	return data, nil
+}
+
+// pushBlob uploads a blob to a repository in a single request, if not there
+// yet, and returns its descriptor
+func (registry *ociRegistry) pushBlob(repository, mediaType string, data []byte) (*ociDescriptor, error) {
+	descriptor := &ociDescriptor{
+		MediaType: mediaType,
+		Digest:    ociDigest(data),
+		Size:      int64(len(data)),
+	}
+
+	response, err := registry.send(http.MethodHead, "/v2/"+repository+"/blobs/"+descriptor.Digest, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode == http.StatusOK {
+		return descriptor, nil
+	}
+
+	response, err = registry.send(http.MethodPost, "/v2/"+repository+"/blobs/uploads/", nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	err = checkStatus(response, http.StatusAccepted)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		return nil, err
+	}
+
+	query := location.Query()
+	query.Set("digest", descriptor.Digest)
+	location.RawQuery = query.Encode()
+
+	response, err = registry.send(http.MethodPut, location.String(), nil, "application/octet-stream", data)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	err = checkStatus(response, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	return descriptor, nil
+}
+
+// pushManifest uploads a manifest to a repository with a tag, and returns its
+// digest
+func (registry *ociRegistry) pushManifest(repository, tag string, manifest []byte) (string, error) {
+	response, err := registry.send(http.MethodPut, "/v2/"+repository+"/manifests/"+tag, nil, ociManifestMediaType, manifest)
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+
+	err = checkStatus(response, http.StatusCreated)
+	if err != nil {
+		return "", err
+	}
+
+	return ociDigest(manifest), nil
+}
+
+// send sends a request to the registry, relative to its base URL unless
+// absolute. Requests the registry rejects with a bearer token challenge are
+// retried once with a token issued for the challenge scope
+func (registry *ociRegistry) send(method, target string, headers map[string]string, contentType string, body []byte) (*http.Response, error) {
+	location, err := registry.base.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(registry.ctx, method, location.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+
+		registry.authorize(request)
+
+		response, err := registry.client.Do(request)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return response, nil
+		}
+
+		challenge := response.Header.Get("WWW-Authenticate")
+		response.Body.Close()
+
+		err = registry.authenticate(challenge)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// authorize sets the request credentials, preferring the bearer token
+func (registry *ociRegistry) authorize(request *http.Request) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if registry.token != "" {
+		request.Header.Set("Authorization", "Bearer "+registry.token)
+		return
+	}
+
+	if registry.auth != nil {
+		registry.auth.SetAuth(request)
+	}
+}
+
+// authenticate fetches a bearer token as requested by a challenge
+func (registry *ociRegistry) authenticate(challenge string) error {
+	scheme, params := parseChallenge(challenge)
+
+	if !strings.EqualFold(scheme, "bearer") {
+		if registry.auth == nil {
+			return fmt.Errorf("registry %s requires authentication", registry.base.Host)
+		}
+
+		return fmt.Errorf("registry %s rejected the credentials", registry.base.Host)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("registry %s sent an invalid token realm", registry.base.Host)
+	}
+
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+
+	realm.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(registry.ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	if registry.auth != nil {
+		registry.auth.SetAuth(request)
+	}
+
+	response, err := registry.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	err = checkStatus(response, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("registry %s token: %w", registry.base.Host, err)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&token)
+	if err != nil {
+		return err
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	if token.Token == "" {
+		return fmt.Errorf("registry %s issued no token", registry.base.Host)
+	}
+
+	registry.mutex.Lock()
+	registry.token = token.Token
+	registry.mutex.Unlock()
+
+	return nil
+}
+
+// parseChallenge returns the scheme and parameters of a WWW-Authenticate
+// header, e.g. Bearer realm="https://auth.example.com/token",service="registry"
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+
+	for rest != "" {
+		var key string
+
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if key == "" {
+			break
+		}
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				end = len(rest) - 1
+			}
+
+			value, rest = rest[1:end+1], rest[end+1:]
+			rest = strings.TrimPrefix(rest, `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return scheme, params
+}
+
+// checkStatus fails if the response status is not the expected one
+func checkStatus(response *http.Response, status int) error {
+	if response.StatusCode == status {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+
+	err := fmt.Errorf("%s %s: %s", response.Request.Method, response.Request.URL.Redacted(), response.Status)
+
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", err, message)
+}
+
+// ociDigest returns the sha256 digest of the data
+func ociDigest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
@@ -0,0 +1,286 @@
+package maker_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+const testRegistryToken = "t0k3n"
+
+// testRegistry is an in-memory stand-in of the OCI distribution API, which
+// issues bearer tokens to clients with the basic credentials
+type testRegistry struct {
+	username, password string
+
+	mutex     sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string]map[string][]byte
+}
+
+func newTestRegistry(t *testing.T, username, password string) *httptest.Server {
+	t.Helper()
+
+	registry := &testRegistry{
+		username:  username,
+		password:  password,
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string]map[string][]byte),
+	}
+
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func (registry *testRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if r.URL.Path == "/token" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != registry.username || pass != registry.password {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": testRegistryToken})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+testRegistryToken {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test",scope="repository:any:pull,push"`, r.Host))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	route := strings.TrimPrefix(r.URL.Path, "/v2/")
+
+	switch {
+	case strings.HasSuffix(route, "/tags/list"):
+		repository := strings.TrimSuffix(route, "/tags/list")
+		if registry.manifests[repository] == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		tags := make([]string, 0)
+		for reference := range registry.manifests[repository] {
+			if !strings.HasPrefix(reference, "sha256:") {
+				tags = append(tags, reference)
+			}
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": repository, "tags": tags})
+	case strings.Contains(route, "/manifests/"):
+		index := strings.LastIndex(route, "/manifests/")
+		repository, reference := route[:index], route[index+len("/manifests/"):]
+
+		if r.Method == http.MethodPut {
+			data, _ := io.ReadAll(r.Body)
+			digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+
+			if registry.manifests[repository] == nil {
+				registry.manifests[repository] = make(map[string][]byte)
+			}
+
+			registry.manifests[repository][reference] = data
+			registry.manifests[repository][digest] = data
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		data, exists := registry.manifests[repository][reference]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write(data)
+	case strings.HasSuffix(route, "/blobs/uploads/"):
+		w.Header().Set("Location", "/v2/"+route+"upload")
+		w.WriteHeader(http.StatusAccepted)
+	case strings.HasSuffix(route, "/blobs/uploads/upload"):
+		data, _ := io.ReadAll(r.Body)
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+
+		if digest != r.URL.Query().Get("digest") {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+
+		registry.blobs[digest] = data
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(route, "/blobs/"):
+		data, exists := registry.blobs[route[strings.LastIndex(route, "/")+1:]]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write(data)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestOCISource(t *testing.T) {
+	server := newTestRegistry(t, "ci", "s3cr3t")
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	t.Setenv("MAKER_TEST_REGISTRY_PASSWORD", "s3cr3t")
+
+	auth := &maker.Auth{
+		Method:      maker.AuthBasic,
+		User:        "ci",
+		PasswordEnv: "MAKER_TEST_REGISTRY_PASSWORD",
+	}
+
+	for version, contents := range map[string]string{
+		"1.0.0": "one\n",
+		"1.1.0": "one dot one\n",
+		"2.0.0": "two\n",
+	} {
+		_, err := maker.PushSnippet(maker.PushOptions{
+			Reference: "oci+http://" + host + "/ns/snippets/golang:" + version,
+			Contents:  []byte(contents),
+			Auth:      auth,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := maker.PushSnippet(maker.PushOptions{
+		Reference: "oci+http://" + host + "/ns/snippets/golang:3.0.0",
+		Contents:  []byte("anonymous\n"),
+	})
+	if err == nil {
+		t.Error("got no error pushing without credentials")
+	}
+
+	config := "repositories:\n- alias: registry\n  url: oci+http://" + host + "/ns/snippets\n  auth:\n    method: basic\n    user: ci\n    passwordEnv: MAKER_TEST_REGISTRY_PASSWORD\n  snippets: {}\n"
+
+	conf := maker.NewMemoryFile([]byte(config))
+	lock := maker.NewMemoryFile(nil)
+	directory := memfs.New()
+
+	mk, err := maker.New(conf, lock, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("registry:golang@^1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := util.ReadFile(directory, "golang.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one dot one\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "one dot one\n")
+	}
+
+	for _, want := range []string{"version: 1.1.0", "commit: golang@sha256:"} {
+		if !strings.Contains(string(lock.Bytes()), want) {
+			t.Errorf("lock does not contain %q:\n%s", want, lock.Bytes())
+		}
+	}
+
+	outdated, err := mk.Outdated()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outdated) != 1 || outdated[0].Latest != "2.0.0" {
+		t.Errorf("got outdated snippets %+v, want golang with latest 2.0.0", outdated)
+	}
+
+	err = mk.Add("registry:missing")
+	if err == nil {
+		t.Error("got no error adding a snippet missing from the registry")
+	}
+}
+
+func TestOCIDigestPin(t *testing.T) {
+	server := newTestRegistry(t, "ci", "s3cr3t")
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	t.Setenv("MAKER_TEST_REGISTRY_PASSWORD", "s3cr3t")
+
+	auth := &maker.Auth{
+		Method:      maker.AuthBasic,
+		User:        "ci",
+		PasswordEnv: "MAKER_TEST_REGISTRY_PASSWORD",
+	}
+
+	digests := make(map[string]string)
+
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		digest, err := maker.PushSnippet(maker.PushOptions{
+			Reference: "oci+http://" + host + "/ns/snippets/golang:" + version,
+			Contents:  []byte(version + "\n"),
+			Auth:      auth,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		digests[version] = digest
+	}
+
+	config := "repositories:\n- alias: registry\n  url: oci+http://" + host + "/ns/snippets\n  auth:\n    method: basic\n    user: ci\n    passwordEnv: MAKER_TEST_REGISTRY_PASSWORD\n  snippets: {}\n"
+
+	// commits are either the locked revisions or the bare digests
+	for _, pin := range []string{"commit:golang@" + digests["1.0.0"], "commit:" + digests["1.0.0"]} {
+		lock := maker.NewMemoryFile(nil)
+		directory := memfs.New()
+
+		mk, err := maker.New(maker.NewMemoryFile([]byte(config)), lock, directory)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mk.Add("registry:golang@" + pin)
+		if err != nil {
+			t.Fatalf("%s: %v", pin, err)
+		}
+
+		data, err := util.ReadFile(directory, "golang.mk")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != "1.0.0\n" {
+			t.Errorf("%s: got snippet contents %q, want %q", pin, data, "1.0.0\n")
+		}
+
+		if !strings.Contains(string(lock.Bytes()), "commit: golang@"+digests["1.0.0"]) {
+			t.Errorf("%s: lock does not contain the digest:\n%s", pin, lock.Bytes())
+		}
+	}
+
+	mk, err := maker.New(maker.NewMemoryFile([]byte(config)), maker.NewMemoryFile(nil), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("registry:golang@commit:other@" + digests["1.0.0"])
+	if err == nil || !strings.Contains(err.Error(), "not a revision of snippet golang") {
+		t.Errorf("got error %v, want the digest of another snippet rejected", err)
+	}
+}
@@ -0,0 +1,30 @@
+package maker
+
+import "fmt"
+
+// MergeStrategy defines how local modifications of snippet files are handled
+// when installing their upstream contents
+type MergeStrategy string
+
+const (
+	// StrategyMerge performs a three-way merge between the previous upstream
+	// contents, the new upstream contents and the local file, writing conflict
+	// markers on overlapping changes
+	StrategyMerge MergeStrategy = "merge"
+	// StrategyOurs keeps the local file as-is
+	StrategyOurs MergeStrategy = "ours"
+	// StrategyTheirs overwrites the local file with the upstream contents
+	StrategyTheirs MergeStrategy = "theirs"
+	// StrategyFail refuses to change locally modified files
+	StrategyFail MergeStrategy = "fail"
+)
+
+// ParseMergeStrategy returns the merge strategy with the given name
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	switch strategy := MergeStrategy(name); strategy {
+	case StrategyMerge, StrategyOurs, StrategyTheirs, StrategyFail:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown merge strategy %s", name)
+	}
+}
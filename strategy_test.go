@@ -0,0 +1,118 @@
+package maker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+func TestMergeStrategies(t *testing.T) {
+	url := localSnippetsRepository(t, map[string]string{
+		"1.0.0": "one\ntwo\nthree\nfour\nfive\n",
+		"2.0.0": "one\ntwo\nthree\nfour\nfive\nsix\n",
+	})
+
+	snippet := maker.SnippetsDirectory + "/go.mk"
+	local := "ONE\ntwo\nthree\nfour\nfive\n"
+
+	tests := []struct {
+		strategy string
+		want     string
+		err      string
+	}{
+		{strategy: "theirs", want: "one\ntwo\nthree\nfour\nfive\nsix\n"},
+		{strategy: "ours", want: local},
+		{strategy: "merge", want: "ONE\ntwo\nthree\nfour\nfive\nsix\n"},
+		{strategy: "fail", want: local, err: "snippet go has local modifications"},
+	}
+
+	for _, test := range tests {
+		strategy, err := maker.ParseMergeStrategy(test.strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		project := memfs.New()
+
+		err = util.WriteFile(project, maker.ConfFilename, pinConfig(url, "=1.0.0"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = newProjectMaker(t, project).Install(false, maker.StrategyTheirs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = util.WriteFile(project, snippet, []byte(local), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// locally modified snippets are left alone while there are no upstream
+		// changes, unless overwritten
+		err = newProjectMaker(t, project).Install(false, strategy)
+		if err != nil {
+			t.Errorf("%s: got error %v installing without upstream changes", test.strategy, err)
+		}
+
+		data, err := util.ReadFile(project, snippet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := local
+		if strategy == maker.StrategyTheirs {
+			want = "one\ntwo\nthree\nfour\nfive\n"
+
+			err = util.WriteFile(project, snippet, []byte(local), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if string(data) != want {
+			t.Errorf("%s: got snippet contents %q installing, want %q", test.strategy, data, want)
+		}
+
+		lockData, err := util.ReadFile(project, maker.LockFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = util.WriteFile(project, maker.ConfFilename, pinConfig(url, "*"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = newProjectMaker(t, project).Update(strategy)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: got error %v updating", test.strategy, err)
+		}
+
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: got error %v updating, want %q", test.strategy, err, test.err)
+		}
+
+		data, err = util.ReadFile(project, snippet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != test.want {
+			t.Errorf("%s: got snippet contents %q updating, want %q", test.strategy, data, test.want)
+		}
+
+		data, err = util.ReadFile(project, maker.LockFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.err != "" && string(data) != string(lockData) {
+			t.Errorf("%s: got lock written despite the error:\n%s", test.strategy, data)
+		}
+	}
+}
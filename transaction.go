@@ -0,0 +1,144 @@
+package maker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// transaction replaces the contents of a set of files as a unit, restoring the
+// previous contents of every replaced file if any replacement fails
+type transaction struct {
+	rollbacks []func() error
+}
+
+// replaceFile swaps the whole contents of a file descriptor, atomically if it
+// is Replaceable
+func (tx *transaction) replaceFile(file File, data []byte) error {
+	_, err := file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	previousData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	tx.rollbacks = append(tx.rollbacks, func() error {
+		return replaceContents(file, previousData)
+	})
+
+	return replaceContents(file, data)
+}
+
+// writeFile writes a file on the filesystem atomically, or removes it if the
+// data is nil
+func (tx *transaction) writeFile(filesystem billy.Filesystem, filename string, data []byte) error {
+	previousData, existed, err := readFilesystemFile(filesystem, filename)
+	if err != nil {
+		return err
+	}
+
+	tx.rollbacks = append(tx.rollbacks, func() error {
+		if !existed {
+			return removeFile(filesystem, filename)
+		}
+
+		return writeFileAtomic(filesystem, filename, previousData, 0640)
+	})
+
+	if data == nil {
+		return removeFile(filesystem, filename)
+	}
+
+	return writeFileAtomic(filesystem, filename, data, 0640)
+}
+
+// rollback restores all files replaced so far, in reverse order
+func (tx *transaction) rollback() error {
+	var err error
+
+	for index := len(tx.rollbacks) - 1; index >= 0; index-- {
+		rollbackErr := tx.rollbacks[index]()
+		if rollbackErr != nil && err == nil {
+			err = rollbackErr
+		}
+	}
+
+	tx.rollbacks = nil
+
+	return err
+}
+
+// abort restores all files replaced so far, and returns the error that caused
+// the abort along with any restore failure
+func (tx *transaction) abort(err error) error {
+	rollbackErr := tx.rollback()
+	if rollbackErr != nil {
+		return fmt.Errorf("%w (restoring previous files also failed: %s)", err, rollbackErr)
+	}
+
+	return err
+}
+
+// replaceContents swaps the whole contents of a file descriptor
+func replaceContents(file File, data []byte) error {
+	if replaceable, ok := file.(Replaceable); ok {
+		return replaceable.Replace(data)
+	}
+
+	err := file.Truncate(0)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		return err
+	}
+
+	if syncer, ok := file.(Syncable); ok {
+		return syncer.Sync()
+	}
+
+	return nil
+}
+
+// readFilesystemFile returns the file contents, and false if it does not exist
+func readFilesystemFile(filesystem billy.Filesystem, filename string) ([]byte, bool, error) {
+	fd, err := filesystem.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+	defer fd.Close()
+
+	data, err := io.ReadAll(fd)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// removeFile removes the file, if it exists
+func removeFile(filesystem billy.Filesystem, filename string) error {
+	err := filesystem.Remove(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,108 @@
+package maker_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+var errReplace = errors.New("disk full")
+
+// failingFile is a file that can be read but not replaced
+type failingFile struct {
+	billy.File
+}
+
+func (file failingFile) Replace(data []byte) error {
+	return errReplace
+}
+
+func TestTransactionRollback(t *testing.T) {
+	url := localSnippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+	})
+
+	for _, existing := range []bool{true, false} {
+		project := memfs.New()
+
+		err := util.WriteFile(project, maker.ConfFilename, pinConfig(url, "^1"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if existing {
+			err = util.WriteFile(project, maker.LockFilename, []byte("lockfileVersion: 2\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = util.WriteFile(project, maker.SnippetsDirectory+"/go.mk", []byte("local\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		conf, err := project.OpenFile(maker.ConfFilename, os.O_RDWR, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lock, err := project.OpenFile(maker.LockFilename, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		directory, err := project.Chroot(maker.SnippetsDirectory)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the configuration is written last, after the snippet and lock files
+		mk, err := maker.New(failingFile{conf}, lock, directory)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mk.Install(false, maker.StrategyTheirs)
+		if !errors.Is(err, errReplace) {
+			t.Fatalf("got error %v, want %v", err, errReplace)
+		}
+
+		lockData, err := util.ReadFile(project, maker.LockFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := util.ReadFile(directory, "go.mk")
+
+		if !existing {
+			if len(lockData) != 0 {
+				t.Errorf("got lock not restored:\n%s", lockData)
+			}
+
+			// new files are removed
+			if err == nil {
+				t.Error("got new snippet file kept after the rollback")
+			}
+
+			continue
+		}
+
+		if string(lockData) != "lockfileVersion: 2\n" {
+			t.Errorf("got lock not restored:\n%s", lockData)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != "local\n" {
+			t.Errorf("got snippet contents %q, want the restored %q", data, "local\n")
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package maker
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UserConfigFilename is the user configuration file, relative to the user
+// configuration directory, e.g. $XDG_CONFIG_HOME/maker/config.yaml
+const UserConfigFilename = "maker/config.yaml"
+
+// UserConfig stores the settings of the current user that apply to all
+// projects. Project settings take precedence over the user ones, except for
+// the mirrors and default repository, which depend on the user environment:
+//
+//   - project repositories shadow user ones with the same alias or URL
+//   - the auth set on a project repository replaces the user credentials,
+//     unless a mirror fetches it from another host
+//   - the default repository is used to add snippets without an alias, instead
+//     of the first project repository
+//   - user mirrors win over project ones matching prefixes of the same length
+//
+// Command line flags and environment variables take precedence over the cache
+// directory and output settings
+type UserConfig struct {
+	// Repositories are available to add snippets from on every project. Once
+	// a snippet is added the repository is copied to the project configuration,
+	// without its auth
+	Repositories []*Repository `yaml:"repositories,omitempty"`
+	// DefaultRepository is the alias or URL of the repository to add snippets
+	// from when none is given
+	DefaultRepository string `yaml:"defaultRepository,omitempty"`
+	// CacheDirectory keeps the git repositories across runs, if set
+	CacheDirectory string `yaml:"cacheDirectory,omitempty"`
+	// Mirrors rewrite repository URLs before they are fetched
+	Mirrors []Mirror `yaml:"mirrors,omitempty"`
+	// Credentials authenticate repositories without auth set on the project
+	Credentials []Credential `yaml:"credentials,omitempty"`
+	// Output sets the command line output preferences
+	Output OutputConfig `yaml:"output,omitempty"`
+}
+
+// Credential sets the auth of the repositories fetched from URLs with a prefix,
+// on the same scheme and host and up to a path segment boundary. The longest
+// matching prefix wins
+type Credential struct {
+	URL  string `yaml:"url"`
+	Auth `yaml:",inline"`
+}
+
+// OutputConfig sets the command line output preferences
+type OutputConfig struct {
+	// Color is either auto, always or never
+	Color string `yaml:"color,omitempty"`
+	// LogFormat is the diagnostics format, either text or json
+	LogFormat string `yaml:"logFormat,omitempty"`
+}
+
+// auth returns the user auth settings of a repository, fetched from the URL,
+// or nil if there are none. Repository auth only applies to URLs on the same
+// host, and credentials are matched against the URL, so mirrors on other hosts
+// never receive the repository ones
+func (config *UserConfig) auth(repository *Repository, url string) *Auth {
+	for _, candidate := range config.Repositories {
+		if candidate.URL == repository.URL && candidate.Auth != nil && sameOrigin(candidate.URL, url) {
+			return candidate.Auth
+		}
+	}
+
+	_, location := splitSourceURL(url)
+
+	var (
+		auth        *Auth
+		matchLength int
+	)
+
+	for index, credential := range config.Credentials {
+		if credentialMatches(credential.URL, location) && len(credential.URL) >= matchLength {
+			auth, matchLength = &config.Credentials[index].Auth, len(credential.URL)
+		}
+	}
+
+	return auth
+}
+
+// sameOrigin returns true if both repository URLs are on the same scheme and
+// host, so the auth of one of them can be sent to the other
+func sameOrigin(a, b string) bool {
+	_, a = splitSourceURL(a)
+	_, b = splitSourceURL(b)
+
+	if a == b {
+		return true
+	}
+
+	aScheme, aHost, aFound := locationOrigin(a)
+	bScheme, bHost, bFound := locationOrigin(b)
+
+	return aFound && bFound &&
+		strings.EqualFold(aScheme, bScheme) &&
+		strings.EqualFold(aHost, bHost)
+}
+
+// locationOrigin returns the scheme and host of a location, with scp-like git
+// locations, e.g. git@example.com:org/snippets.git, using ssh
+func locationOrigin(location string) (string, string, bool) {
+	locationURL, err := url.Parse(location)
+	if err == nil && locationURL.Host != "" {
+		return locationURL.Scheme, locationURL.Host, true
+	}
+
+	host, _, found := strings.Cut(location, ":")
+	if !found || host == "" || strings.Contains(host, "/") {
+		return "", "", false
+	}
+
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+
+	return "ssh", host, host != ""
+}
+
+// credentialMatches returns true if the credential URL prefix covers the
+// location. URLs must have the same scheme and host, and the prefix must end at
+// a path segment boundary, so credentials are not sent to lookalike hosts such
+// as git.example.com.attacker.io
+func credentialMatches(prefix, location string) bool {
+	prefixURL, err := url.Parse(prefix)
+	if err != nil || prefixURL.Host == "" {
+		return pathPrefix(location, prefix)
+	}
+
+	locationURL, err := url.Parse(location)
+	if err != nil || locationURL.Host == "" {
+		return false
+	}
+
+	return strings.EqualFold(prefixURL.Scheme, locationURL.Scheme) &&
+		strings.EqualFold(prefixURL.Host, locationURL.Host) &&
+		pathPrefix(locationURL.Path, prefixURL.Path)
+}
+
+// pathPrefix returns true if the prefix is a whole number of path segments of
+// the path, with scp-like git locations, e.g. git@example.com:org, separating
+// the host and path by a colon
+func pathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	if len(path) == len(prefix) || prefix == "" || strings.HasSuffix(prefix, "/") || strings.HasSuffix(prefix, ":") {
+		return true
+	}
+
+	return path[len(prefix)] == '/'
+}
+
+// UserConfigPath returns the user configuration file path
+func UserConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(UserConfigFilename)), nil
+}
+
+// LoadUserConfig reads the user configuration file, returning an empty
+// configuration if it does not exist
+func LoadUserConfig() (*UserConfig, error) {
+	config := &UserConfig{}
+
+	filename, err := UserConfigPath()
+	if err != nil {
+		return config, nil
+	}
+
+	fd, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	err = unmarshalInto(fd, config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return config, nil
+}
+
+// WithCacheDirectory sets where git repositories are kept across runs, instead
+// of the user configuration one
+func WithCacheDirectory(dir string) Option {
+	return func(o *options) {
+		o.cacheDirectory = dir
+	}
+}
+
+// WithUserConfig applies the user configuration, such as one returned by
+// LoadUserConfig
+func WithUserConfig(config *UserConfig) Option {
+	return func(o *options) {
+		o.userConfig = config
+	}
+}
@@ -0,0 +1,218 @@
+package maker_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/wwmoraes/maker"
+)
+
+func TestUserConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	filename, err := maker.UserConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := `defaultRepository: shared
+repositories:
+- alias: shared
+  url: https://example.com/shared.git
+  auth:
+    method: token
+    tokenEnv: MAKER_TEST_SHARED_TOKEN
+  snippets: {}
+credentials:
+- url: https://example.com/
+  method: basic
+  user: ci
+  passwordEnv: MAKER_TEST_PASSWORD
+output:
+  color: never
+`
+
+	err = os.MkdirAll(filepath.Dir(filename), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filename, []byte(config), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userConfig, err := maker.LoadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if userConfig.Output.Color != "never" || len(userConfig.Credentials) != 1 || userConfig.Credentials[0].User != "ci" {
+		t.Errorf("got user configuration %+v", userConfig)
+	}
+
+	fetched := make(map[string]maker.FetchOptions)
+	repository := snippetsRepository(t, map[string]string{"1.0.0": "one\n"})
+	factory := func(options maker.FetchOptions) (maker.Source, error) {
+		fetched[options.URL] = options
+		return repository(options)
+	}
+
+	t.Setenv("MAKER_TEST_SHARED_TOKEN", "t0k3n")
+	t.Setenv("MAKER_TEST_PASSWORD", "s3cr3t")
+
+	conf := maker.NewMemoryFile([]byte(testConfig))
+
+	mk, err := maker.New(conf, maker.NewMemoryFile(nil), memfs.New(), maker.WithRepositoryFactory(factory), maker.WithUserConfig(userConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the default repository is on the user configuration only
+	err = mk.Add("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Add("test:go@^1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := string(conf.Bytes())
+	if !strings.Contains(data, "alias: shared") || strings.Contains(data, "MAKER_TEST_SHARED_TOKEN") {
+		t.Errorf("got configuration without the shared repository or with its auth:\n%s", data)
+	}
+
+	if auth := fetched["https://example.com/shared.git"].Auth; auth == nil || !strings.Contains(auth.String(), "git:") {
+		t.Errorf("got auth %v for the user repository, want its token", auth)
+	}
+
+	if auth := fetched["https://example.com/snippets.git"].Auth; auth == nil || !strings.Contains(auth.String(), "ci:") {
+		t.Errorf("got auth %v for the project repository, want the user credentials", auth)
+	}
+}
+
+func TestUserCredentialsPrefix(t *testing.T) {
+	t.Setenv("MAKER_TEST_PASSWORD", "s3cr3t")
+
+	userConfig := &maker.UserConfig{
+		Credentials: []maker.Credential{
+			{URL: "https://git.example.com", Auth: maker.Auth{Method: maker.AuthBasic, User: "ci", PasswordEnv: "MAKER_TEST_PASSWORD"}},
+			{URL: "https://other.example.com/org", Auth: maker.Auth{Method: maker.AuthBasic, User: "org", PasswordEnv: "MAKER_TEST_PASSWORD"}},
+		},
+	}
+
+	tests := map[string]string{
+		"https://git.example.com/snippets.git":                "ci:",
+		"https://GIT.example.com/team/snippets.git":           "ci:",
+		"https://git.example.com.attacker.io/snippets.git":    "",
+		"https://git.example.com-evil/snippets.git":           "",
+		"https://git.example.com:8443/snippets.git":           "",
+		"http://git.example.com/snippets.git":                 "",
+		"https://other.example.com/org/snippets.git":          "org:",
+		"https://other.example.com/organization/snippets.git": "",
+	}
+
+	for url, want := range tests {
+		var credentials string
+
+		repository := snippetsRepository(t, map[string]string{"1.0.0": "one\n"})
+		factory := func(options maker.FetchOptions) (maker.Source, error) {
+			if options.Auth != nil {
+				credentials = options.Auth.String()
+			}
+
+			return repository(options)
+		}
+
+		mk, err := maker.New(maker.NewMemoryFile([]byte(localConfig(url))), maker.NewMemoryFile(nil), memfs.New(), maker.WithRepositoryFactory(factory), maker.WithUserConfig(userConfig))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mk.Add("local:go")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case want == "" && credentials != "":
+			t.Errorf("%s: got credentials %s, want none", url, credentials)
+		case want != "" && !strings.Contains(credentials, want):
+			t.Errorf("%s: got credentials %q, want %s", url, credentials, want)
+		}
+	}
+}
+
+func TestMirrorAuth(t *testing.T) {
+	t.Setenv("MAKER_TEST_TOKEN", "t0k3n")
+	t.Setenv("MAKER_TEST_PASSWORD", "s3cr3t")
+
+	userConfig := &maker.UserConfig{
+		Credentials: []maker.Credential{
+			{URL: "https://mirror.example.net/", Auth: maker.Auth{Method: maker.AuthBasic, User: "mirror", PasswordEnv: "MAKER_TEST_PASSWORD"}},
+		},
+	}
+
+	tests := map[string]string{
+		// the repository auth is meant for its own host only
+		"https://mirror.example.org/": "",
+		"https://mirror.example.net/": "mirror:",
+		"https://example.com/mirror/": "git:",
+	}
+
+	for mirror, want := range tests {
+		var (
+			fetched     string
+			credentials string
+		)
+
+		repository := snippetsRepository(t, map[string]string{"1.0.0": "one\n"})
+		factory := func(options maker.FetchOptions) (maker.Source, error) {
+			fetched = options.URL
+			if options.Auth != nil {
+				credentials = options.Auth.String()
+			}
+
+			return repository(options)
+		}
+
+		config := `repositories:
+- alias: private
+  url: https://example.com/snippets.git
+  auth:
+    method: token
+    tokenEnv: MAKER_TEST_TOKEN
+  snippets: {}
+mirrors:
+- url: ` + mirror + `
+  insteadOf:
+  - https://example.com/
+`
+
+		mk, err := maker.New(maker.NewMemoryFile([]byte(config)), maker.NewMemoryFile(nil), memfs.New(), maker.WithRepositoryFactory(factory), maker.WithUserConfig(userConfig))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mk.Add("private:go")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fetched != mirror+"snippets.git" {
+			t.Errorf("%s: fetched %s, want the mirror", mirror, fetched)
+		}
+
+		switch {
+		case want == "" && credentials != "":
+			t.Errorf("%s: got credentials %s, want none", mirror, credentials)
+		case want != "" && !strings.Contains(credentials, want):
+			t.Errorf("%s: got credentials %q, want %s", mirror, credentials, want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package maker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// VendorDirectory is where the locked snippet contents are exported for
+	// offline use, within the snippets directory
+	VendorDirectory = "vendor"
+	// VendorIndexFilename lists the vendored snippets, within the vendor
+	// directory
+	VendorIndexFilename = "index.yaml"
+)
+
+// vendorIndex maps repository URLs and snippet names to their vendored
+// revision. Contents are stored once per blob hash
+type vendorIndex struct {
+	Repositories map[string]map[string]vendorEntry `yaml:"repositories"`
+}
+
+// vendorEntry is the locked revision of a vendored snippet, with the
+// references it was resolved from
+type vendorEntry struct {
+	Commit  string `yaml:"commit"`
+	Version string `yaml:"version,omitempty"`
+	Tag     string `yaml:"tag,omitempty"`
+	Hash    string `yaml:"hash"`
+}
+
+func vendorIndexPath() string {
+	return path.Join(VendorDirectory, VendorIndexFilename)
+}
+
+func vendorBlobsPath() string {
+	return path.Join(VendorDirectory, "blobs")
+}
+
+func vendorBlobPath(hash string) string {
+	return path.Join(vendorBlobsPath(), hash)
+}
+
+// Vendor exports the locked upstream contents of every snippet from remote
+// repositories to the vendor directory, so they can be installed offline.
+// Local repositories are available offline already, so they are not vendored.
+// Blobs that are no longer locked are removed
+func (mk *Maker) Vendor() error {
+	return mk.VendorContext(context.Background())
+}
+
+// VendorContext is like Vendor, stopping if the context is done, in which case
+// nothing is written
+func (mk *Maker) VendorContext(ctx context.Context) (err error) {
+	defer mk.restoreOnError(mk.snapshot(), &err)
+
+	index := vendorIndex{Repositories: make(map[string]map[string]vendorEntry)}
+	blobs := make(map[string][]byte)
+
+	for _, repository := range mk.conf.Repositories {
+		if len(repository.Snippets) == 0 || isLocalSource(repository.URL) {
+			continue
+		}
+
+		err := mk.initRepository(ctx, repository)
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(repository.Snippets))
+		for name := range repository.Snippets {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		snippets := make(map[string]vendorEntry, len(names))
+
+		for _, name := range names {
+			entry := mk.lock.Get(repository.URL, name)
+			if entry == nil {
+				return fmt.Errorf("snippet %s is not locked, install it before vendoring", name)
+			}
+
+			data, err := mk.upstreamContents(repository, name, entry.Commit)
+			if err != nil {
+				return err
+			}
+
+			hash := blobHash(data)
+			if entry.Hash != "" && entry.Hash != hash {
+				return fmt.Errorf("snippet %s contents at %s do not match the locked hash %s", name, entry.Commit, entry.Hash)
+			}
+
+			blobs[hash] = data
+			snippets[name] = vendorEntry{
+				Commit:  entry.Commit,
+				Version: entry.Version,
+				Tag:     entry.Tag,
+				Hash:    hash,
+			}
+
+			mk.emit(Event{
+				Kind:       EventVendored,
+				Repository: repository.URL,
+				Snippet:    name,
+				Version:    entry.Version,
+				Commit:     entry.Commit,
+			})
+		}
+
+		index.Repositories[repository.URL] = snippets
+	}
+
+	infos, err := mk.directory.ReadDir(vendorBlobsPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, info := range infos {
+		if _, needed := blobs[info.Name()]; !needed {
+			mk.pending[vendorBlobPath(info.Name())] = nil
+		}
+	}
+
+	for hash, data := range blobs {
+		mk.pending[vendorBlobPath(hash)] = data
+	}
+
+	data, err := yaml.Marshal(&index)
+	if err != nil {
+		return err
+	}
+
+	mk.pending[vendorIndexPath()] = data
+
+	return mk.commit(ctx)
+}
+
+// vendorSource returns the vendored snippets of a repository, failing if there
+// are none
+func (mk *Maker) vendorSource(url string) (Source, error) {
+	data, exists, err := mk.readFile(vendorIndexPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var index vendorIndex
+
+	if exists {
+		err = yaml.Unmarshal(data, &index)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Join(mk.snippetsDirectory, vendorIndexPath()), err)
+		}
+	}
+
+	snippets, vendored := index.Repositories[url]
+	if !vendored {
+		return nil, fmt.Errorf("offline mode: repository %s is not vendored, run maker vendor while online", url)
+	}
+
+	return &vendoredSource{mk: mk, url: url, snippets: snippets}, nil
+}
+
+// vendoredSource provides the snippets exported by Maker.Vendor. Each snippet
+// has its locked revision only, which its pin must still resolve to
+type vendoredSource struct {
+	mk       *Maker
+	url      string
+	snippets map[string]vendorEntry
+}
+
+// Versions implements Source
+func (source *vendoredSource) Versions(snippet string) ([]string, error) {
+	entry, err := source.entry(snippet)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Tag == "" {
+		return []string{}, nil
+	}
+
+	return []string{entry.Tag}, nil
+}
+
+// Resolve implements Source
+func (source *vendoredSource) Resolve(snippet string, kind PinKind, reference string) (string, error) {
+	entry, err := source.entry(snippet)
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case PinVersion, PinTag, PinBranch:
+		if entry.Tag == reference {
+			return entry.Commit, nil
+		}
+	case PinCommit:
+		if strings.HasPrefix(entry.Commit, reference) {
+			return entry.Commit, nil
+		}
+	}
+
+	return "", fmt.Errorf("offline mode: snippet %s is vendored at %s, not at %s %s", snippet, entryRevision(&LockEntry{Version: entry.Version, Commit: entry.Commit}), kind, reference)
+}
+
+// Get implements Source
+func (source *vendoredSource) Get(id, name string) ([]byte, error) {
+	entry, err := source.entry(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Commit != id {
+		return nil, fmt.Errorf("offline mode: snippet %s is vendored at %s, not at %s", name, entry.Commit, id)
+	}
+
+	data, exists, err := source.mk.readFile(vendorBlobPath(entry.Hash))
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists || blobHash(data) != entry.Hash {
+		return nil, fmt.Errorf("offline mode: vendored snippet %s is missing or modified, run maker vendor while online", name)
+	}
+
+	return data, nil
+}
+
+// Snippets implements Source
+func (source *vendoredSource) Snippets(id string) ([]string, error) {
+	names := make([]string, 0, len(source.snippets))
+
+	for name, entry := range source.snippets {
+		if entry.Commit == id {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (source *vendoredSource) entry(snippet string) (vendorEntry, error) {
+	entry, exists := source.snippets[snippet]
+	if !exists {
+		return entry, fmt.Errorf("offline mode: snippet %s of %s is not vendored, run maker vendor while online", snippet, source.url)
+	}
+
+	return entry, nil
+}
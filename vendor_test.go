@@ -0,0 +1,102 @@
+package maker_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+func TestVendorOffline(t *testing.T) {
+	conf := maker.NewMemoryFile([]byte(testConfig))
+	lock := maker.NewMemoryFile(nil)
+	directory := memfs.New()
+
+	mk, err := maker.New(conf, lock, directory, maker.WithRepositoryFactory(snippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+		"1.1.0": "one dot one\n",
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offlineFactory := maker.WithRepositoryFactory(func(options maker.FetchOptions) (maker.Source, error) {
+		t.Errorf("fetched %s while offline", options.URL)
+		return nil, fmt.Errorf("offline")
+	})
+
+	offline, err := maker.New(maker.NewMemoryFile([]byte(testConfig)), maker.NewMemoryFile(nil), memfs.New(), offlineFactory, maker.WithOffline(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = offline.Add("go@^1")
+	if err == nil || !strings.Contains(err.Error(), "not vendored") {
+		t.Errorf("got error %v, want not vendored error", err)
+	}
+
+	err = mk.Add("go@^1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Vendor()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := util.ReadFile(directory, "vendor/index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(index), "version: 1.1.0") {
+		t.Errorf("vendor index does not contain the locked version:\n%s", index)
+	}
+
+	err = directory.Remove("go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offline, err = maker.New(conf, lock, directory, offlineFactory, maker.WithOffline(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = offline.InstallFrozen(maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := util.ReadFile(directory, "go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one dot one\n" {
+		t.Errorf("got snippet contents %q, want %q", data, "one dot one\n")
+	}
+
+	err = offline.Update(maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outdated, err := offline.Outdated()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outdated) != 0 {
+		t.Errorf("got outdated snippets %+v, want none", outdated)
+	}
+
+	err = offline.Add("node")
+	if err == nil || !strings.Contains(err.Error(), "not vendored") {
+		t.Errorf("got error %v, want not vendored error", err)
+	}
+}
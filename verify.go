@@ -0,0 +1,102 @@
+package maker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Verification reports the differences between the installed snippet files and
+// their locked contents
+type Verification struct {
+	// Missing lists the locked snippets that have no file installed
+	Missing []string
+	// Modified lists the locked snippets whose file contents differ
+	Modified []string
+	// Untracked lists the snippets whose files have no lock entry
+	Untracked []string
+}
+
+// OK returns true if all installed snippets match their locked contents
+func (verification *Verification) OK() bool {
+	return len(verification.Missing) == 0 &&
+		len(verification.Modified) == 0 &&
+		len(verification.Untracked) == 0
+}
+
+// Verify checks every installed snippet file against the blob hash recorded on
+// the lock, including any local patch, without fetching any repository
+func (mk *Maker) Verify() (*Verification, error) {
+	verification := &Verification{}
+	locked := make(map[string]bool)
+
+	for url, snippets := range mk.lock.Repositories {
+		for name, entry := range snippets {
+			// shared locks hold the snippets of other workspace members too
+			if mk.shared != nil {
+				repository := findRepository(mk.conf.Repositories, url)
+				if repository == nil || !repository.HasSnippet(name) {
+					continue
+				}
+			}
+
+			filename := snippetFilename(name)
+			locked[filename] = true
+
+			if entry.Hash == "" {
+				return nil, fmt.Errorf("%s entry for snippet %s has no hash, install it to record one", mk.lockFilename, name)
+			}
+
+			fd, err := mk.directory.Open(filename)
+			if errors.Is(err, os.ErrNotExist) {
+				verification.Missing = append(verification.Missing, name)
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := io.ReadAll(fd)
+			fd.Close()
+			if err != nil {
+				return nil, err
+			}
+
+			// patched snippets are installed with different contents than upstream
+			wantHash := entry.Hash
+			if entry.Patched != "" {
+				wantHash = entry.Patched
+			}
+
+			if blobHash(data) != wantHash {
+				verification.Modified = append(verification.Modified, name)
+			}
+		}
+	}
+
+	infos, err := mk.directory.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || path.Ext(info.Name()) != ".mk" {
+			continue
+		}
+
+		if !locked[info.Name()] {
+			verification.Untracked = append(verification.Untracked, strings.TrimSuffix(info.Name(), ".mk"))
+		}
+	}
+
+	sort.Strings(verification.Missing)
+	sort.Strings(verification.Modified)
+	sort.Strings(verification.Untracked)
+
+	return verification, nil
+}
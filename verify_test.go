@@ -0,0 +1,161 @@
+package maker_test
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/wwmoraes/maker"
+)
+
+func TestVerifyMissingAndUntracked(t *testing.T) {
+	url := localSnippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+	})
+
+	project := memfs.New()
+
+	err := util.WriteFile(project, maker.ConfFilename, pinConfig(url, "^1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mk := newProjectMaker(t, project)
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = project.Remove(maker.SnippetsDirectory + "/go.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = util.WriteFile(project, maker.SnippetsDirectory+"/other.mk", []byte("other\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err := mk.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if verification.OK() {
+		t.Error("got verification OK, want differences")
+	}
+
+	if len(verification.Missing) != 1 || verification.Missing[0] != "go" {
+		t.Errorf("got missing snippets %v, want [go]", verification.Missing)
+	}
+
+	if len(verification.Untracked) != 1 || verification.Untracked[0] != "other" {
+		t.Errorf("got untracked snippets %v, want [other]", verification.Untracked)
+	}
+
+	if len(verification.Modified) != 0 {
+		t.Errorf("got modified snippets %v, want none", verification.Modified)
+	}
+}
+
+func TestVerifyModified(t *testing.T) {
+	url := localSnippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+	})
+
+	project := memfs.New()
+
+	err := util.WriteFile(project, maker.ConfFilename, pinConfig(url, "^1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mk := newProjectMaker(t, project)
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err := mk.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !verification.OK() {
+		t.Errorf("got verification %+v of the installed snippet, want no differences", verification)
+	}
+
+	err = util.WriteFile(project, maker.SnippetsDirectory+"/go.mk", []byte("one, edited\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err = mk.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(verification.Modified) != 1 || verification.Modified[0] != "go" {
+		t.Errorf("got modified snippets %v, want [go]", verification.Modified)
+	}
+
+	if len(verification.Missing) != 0 || len(verification.Untracked) != 0 {
+		t.Errorf("got missing snippets %v and untracked ones %v, want none", verification.Missing, verification.Untracked)
+	}
+}
+
+func TestVerifyPatched(t *testing.T) {
+	url := localSnippetsRepository(t, map[string]string{
+		"1.0.0": "one\ntwo\n",
+	})
+
+	project := memfs.New()
+
+	err := util.WriteFile(project, maker.ConfFilename, pinConfig(url, "^1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mk := newProjectMaker(t, project)
+
+	err = mk.Install(false, maker.StrategyTheirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = util.WriteFile(project, maker.SnippetsDirectory+"/go.mk", []byte("ONE\ntwo\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mk.Patch("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// patched snippets match their patched contents instead of upstream
+	verification, err := mk.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !verification.OK() {
+		t.Errorf("got verification %+v of the patched snippet, want no differences", verification)
+	}
+
+	err = util.WriteFile(project, maker.SnippetsDirectory+"/go.mk", []byte("one\ntwo\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err = mk.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(verification.Modified) != 1 || verification.Modified[0] != "go" {
+		t.Errorf("got modified snippets %v of the unpatched snippet, want [go]", verification.Modified)
+	}
+}
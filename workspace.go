@@ -0,0 +1,550 @@
+package maker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WorkspaceFilename is the workspace configuration file, on the workspace root
+const WorkspaceFilename = "maker-workspace.yaml"
+
+// WorkspaceConfig lists the projects of a workspace
+type WorkspaceConfig struct {
+	// Members are the project directories, relative to the workspace root
+	Members []string `yaml:"members"`
+	// SharedLock makes all members use a single lock file on the workspace
+	// root, so the same snippet is locked to the same revision everywhere
+	SharedLock bool `yaml:"sharedLock,omitempty"`
+}
+
+// Workspace manages several projects at once, such as the ones in a monorepo.
+// Members fetch each repository only once, and optionally share a lock file
+type Workspace struct {
+	Root   string
+	Config WorkspaceConfig
+
+	members []workspaceMember
+	closers []io.Closer
+}
+
+type workspaceMember struct {
+	dir string
+	mk  *Maker
+}
+
+// sharedLock is the lock data and file used by all workspace members
+type sharedLock struct {
+	lock      *Lock
+	file      File
+	workspace *Workspace
+}
+
+// pins returns the pin of the snippet on each member that uses it
+func (shared *sharedLock) pins(url, name string) map[string]Pin {
+	pins := make(map[string]Pin)
+
+	for _, member := range shared.workspace.members {
+		repository, err := member.mk.conf.GetRepository(url)
+		if err != nil {
+			continue
+		}
+
+		pin, exists := repository.Snippets[name]
+		if exists {
+			pins[member.dir] = pin
+		}
+	}
+
+	return pins
+}
+
+// checkPin ensures that the pin matches the one other members use for the
+// snippet, as a shared lock holds a single revision per snippet
+func (shared *sharedLock) checkPin(url, name string, pin Pin) error {
+	for dir, other := range shared.pins(url, name) {
+		if other.String() != pin.String() {
+			return fmt.Errorf("snippet %s is pinned to %s on %s, which a shared lock requires on all members", name, other.String(), dir)
+		}
+	}
+
+	return nil
+}
+
+// FindWorkspace returns the nearest directory from dir upwards that has a
+// workspace configuration file, and false if there is none
+func FindWorkspace(dir string) (string, bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false, err
+	}
+
+	for {
+		_, err = os.Stat(filepath.Join(dir, WorkspaceFilename))
+		if err == nil {
+			return dir, true, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", false, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false, nil
+		}
+
+		dir = parent
+	}
+}
+
+// LoadWorkspaceConfig reads the workspace configuration on the root directory,
+// without opening any of its members
+func LoadWorkspaceConfig(root string) (WorkspaceConfig, error) {
+	var config WorkspaceConfig
+
+	fd, err := os.Open(filepath.Join(root, WorkspaceFilename))
+	if err != nil {
+		return config, err
+	}
+
+	err = unmarshalInto(fd, &config)
+	fd.Close()
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", WorkspaceFilename, err)
+	}
+
+	if len(config.Members) == 0 {
+		return config, fmt.Errorf("%s has no members", WorkspaceFilename)
+	}
+
+	return config, nil
+}
+
+// HasMember returns true if the directory, relative to the workspace root, is
+// one of the members
+func (config WorkspaceConfig) HasMember(dir string) bool {
+	for _, member := range config.Members {
+		if filepath.Clean(member) == filepath.Clean(dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OpenWorkspace opens the workspace on the root directory and all of its
+// members on the OS filesystem, with the options applied to each. The
+// workspace must be closed to release the members
+func OpenWorkspace(root string, opts ...Option) (_ *Workspace, err error) {
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := LoadWorkspaceConfig(root)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &Workspace{Root: root, Config: config}
+	defer func() {
+		if err != nil {
+			closeAll(ws.closers)
+		}
+	}()
+
+	o := newOptions(opts)
+	cache := &repositoryCache{
+		factory:      o.repositoryFactory,
+		repositories: make(map[string]*cachedSource),
+	}
+
+	memberOpts := append([]Option{}, opts...)
+	memberOpts = append(memberOpts, WithRepositoryFactory(cache.get))
+
+	if ws.Config.SharedLock {
+		shared, err := ws.openSharedLock(o.lockFilename)
+		if err != nil {
+			return nil, err
+		}
+
+		memberOpts = append(memberOpts, func(o *options) {
+			o.sharedLock = shared
+		})
+	}
+
+	seen := make(map[string]bool, len(ws.Config.Members))
+
+	for _, dir := range ws.Config.Members {
+		dir = filepath.Clean(dir)
+		if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s member %s is not within the workspace", WorkspaceFilename, dir)
+		}
+
+		if seen[dir] {
+			return nil, fmt.Errorf("%s lists member %s more than once", WorkspaceFilename, dir)
+		}
+
+		seen[dir] = true
+
+		member, err := NewWithOptions(append(memberOpts,
+			WithRoot(filepath.Join(root, dir)),
+			func(o *options) {
+				o.project = filepath.ToSlash(dir)
+			},
+		)...)
+		if err != nil {
+			return nil, fmt.Errorf("member %s: %w", dir, err)
+		}
+
+		ws.closers = append(ws.closers, member)
+		ws.members = append(ws.members, workspaceMember{dir: filepath.ToSlash(dir), mk: member})
+	}
+
+	if ws.Config.SharedLock {
+		err = ws.checkSharedPins()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ws, nil
+}
+
+// openSharedLock opens the workspace lock file shared by all members
+func (ws *Workspace) openSharedLock(filename string) (*sharedLock, error) {
+	fd, err := openAtomicFile(newOSFilesystem(ws.Root), filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.closers = append(ws.closers, fd)
+
+	shared := &sharedLock{
+		lock:      NewLock(),
+		file:      fd,
+		workspace: ws,
+	}
+
+	err = unmarshalInto(fd, shared.lock)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return shared, nil
+}
+
+// checkSharedPins ensures that all members pin the same snippets the same way,
+// as a shared lock holds a single revision per snippet
+func (ws *Workspace) checkSharedPins() error {
+	type pinUser struct {
+		dir string
+		pin string
+	}
+
+	pins := make(map[string]pinUser)
+
+	for _, member := range ws.members {
+		for _, repository := range member.mk.conf.Repositories {
+			for name, pin := range repository.Snippets {
+				key := repository.URL + " " + name
+
+				user, exists := pins[key]
+				if !exists {
+					pins[key] = pinUser{member.dir, pin.String()}
+					continue
+				}
+
+				if user.pin != pin.String() {
+					return fmt.Errorf("snippet %s is pinned to %s on %s and to %s on %s, which a shared lock cannot hold", name, user.pin, user.dir, pin.String(), member.dir)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close releases all members and the shared lock file. It is safe to call
+// multiple times
+func (ws *Workspace) Close() error {
+	closers := ws.closers
+	ws.closers = nil
+
+	return closeAll(closers)
+}
+
+// Members returns the member directories, relative to the workspace root
+func (ws *Workspace) Members() []string {
+	dirs := make([]string, 0, len(ws.members))
+	for _, member := range ws.members {
+		dirs = append(dirs, member.dir)
+	}
+
+	return dirs
+}
+
+// Member returns the instance of a member directory, relative to the workspace
+// root, and false if it is not a member
+func (ws *Workspace) Member(dir string) (*Maker, bool) {
+	dir = filepath.ToSlash(filepath.Clean(dir))
+
+	for _, member := range ws.members {
+		if member.dir == dir {
+			return member.mk, true
+		}
+	}
+
+	return nil, false
+}
+
+// Install installs the snippets of every member, stopping on the first error
+func (ws *Workspace) Install(force bool, strategy MergeStrategy) error {
+	return ws.InstallContext(context.Background(), force, strategy)
+}
+
+// InstallContext is like Install, stopping if the context is done
+func (ws *Workspace) InstallContext(ctx context.Context, force bool, strategy MergeStrategy) error {
+	for _, member := range ws.members {
+		err := member.mk.InstallContext(ctx, force, strategy)
+		if err != nil {
+			return fmt.Errorf("member %s: %w", member.dir, err)
+		}
+	}
+
+	return nil
+}
+
+// Vendor exports the locked snippets of every member to their vendor
+// directories, like Maker.Vendor, stopping on the first error
+func (ws *Workspace) Vendor() error {
+	return ws.VendorContext(context.Background())
+}
+
+// VendorContext is like Vendor, stopping if the context is done
+func (ws *Workspace) VendorContext(ctx context.Context) error {
+	for _, member := range ws.members {
+		err := member.mk.VendorContext(ctx)
+		if err != nil {
+			return fmt.Errorf("member %s: %w", member.dir, err)
+		}
+	}
+
+	return nil
+}
+
+// InstallFrozen installs the snippets of every member exactly as locked, like
+// Maker.InstallFrozen, stopping on the first error
+func (ws *Workspace) InstallFrozen(strategy MergeStrategy) error {
+	return ws.InstallFrozenContext(context.Background(), strategy)
+}
+
+// InstallFrozenContext is like InstallFrozen, stopping if the context is done
+func (ws *Workspace) InstallFrozenContext(ctx context.Context, strategy MergeStrategy) error {
+	if ws.Config.SharedLock {
+		err := ws.checkSharedLockEntries()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, member := range ws.members {
+		err := member.mk.InstallFrozenContext(ctx, strategy)
+		if err != nil {
+			return fmt.Errorf("member %s: %w", member.dir, err)
+		}
+	}
+
+	return nil
+}
+
+// checkSharedLockEntries ensures the shared lock has no entries that no member
+// uses
+func (ws *Workspace) checkSharedLockEntries() error {
+	if len(ws.members) == 0 {
+		return nil
+	}
+
+	lock := ws.members[0].mk.lock
+
+	for url, snippets := range lock.Repositories {
+		for name := range snippets {
+			used := false
+			for _, member := range ws.members {
+				repository, err := member.mk.conf.GetRepository(url)
+				used = used || (err == nil && repository.HasSnippet(name))
+			}
+
+			if !used {
+				return fmt.Errorf("shared lock has an entry for snippet %s of %s not used by any member", name, url)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Update updates the given snippets on every member that has them, or all
+// snippets if none is given, like Maker.Update. It stops on the first error
+func (ws *Workspace) Update(strategy MergeStrategy, names ...string) error {
+	return ws.UpdateContext(context.Background(), strategy, names...)
+}
+
+// UpdateContext is like Update, stopping if the context is done
+func (ws *Workspace) UpdateContext(ctx context.Context, strategy MergeStrategy, names ...string) error {
+	found := make(map[string]bool, len(names))
+
+	for _, member := range ws.members {
+		memberNames := make([]string, 0, len(names))
+		for _, name := range names {
+			_, err := member.mk.conf.GetSnippetRepository(name)
+			if err == nil {
+				memberNames = append(memberNames, name)
+				found[name] = true
+			}
+		}
+
+		if len(names) > 0 && len(memberNames) == 0 {
+			continue
+		}
+
+		err := member.mk.UpdateContext(ctx, strategy, memberNames...)
+		if err != nil {
+			return fmt.Errorf("member %s: %w", member.dir, err)
+		}
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			return fmt.Errorf("snippet %s not found on any member", name)
+		}
+	}
+
+	return nil
+}
+
+// WorkspaceOutdated is an outdated snippet of a workspace member
+type WorkspaceOutdated struct {
+	Outdated
+	Member string
+}
+
+// Outdated returns the outdated snippets of every member, like
+// Maker.Outdated, sorted by member
+func (ws *Workspace) Outdated() ([]WorkspaceOutdated, error) {
+	return ws.OutdatedContext(context.Background())
+}
+
+// OutdatedContext is like Outdated, stopping if the context is done
+func (ws *Workspace) OutdatedContext(ctx context.Context) ([]WorkspaceOutdated, error) {
+	outdated := make([]WorkspaceOutdated, 0)
+
+	for _, member := range ws.members {
+		items, err := member.mk.OutdatedContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("member %s: %w", member.dir, err)
+		}
+
+		for _, item := range items {
+			outdated = append(outdated, WorkspaceOutdated{item, member.dir})
+		}
+	}
+
+	return outdated, nil
+}
+
+// Divergence is a snippet locked to different revisions across members
+type Divergence struct {
+	Repository string
+	Snippet    string
+	// Revisions maps each member directory to the locked version, or commit if
+	// unversioned
+	Revisions map[string]string
+}
+
+// Divergences returns the snippets locked to different revisions across
+// members, sorted by name
+func (ws *Workspace) Divergences() []Divergence {
+	revisions := make(map[[2]string]map[string]string)
+
+	for _, member := range ws.members {
+		for url, snippets := range member.mk.lock.Repositories {
+			for name, entry := range snippets {
+				key := [2]string{url, name}
+				if revisions[key] == nil {
+					revisions[key] = make(map[string]string)
+				}
+
+				revisions[key][member.dir] = entryRevision(entry)
+			}
+		}
+	}
+
+	divergences := make([]Divergence, 0)
+
+	for key, members := range revisions {
+		distinct := make(map[string]bool)
+		for _, revision := range members {
+			distinct[revision] = true
+		}
+
+		if len(distinct) < 2 {
+			continue
+		}
+
+		divergences = append(divergences, Divergence{
+			Repository: key[0],
+			Snippet:    key[1],
+			Revisions:  members,
+		})
+	}
+
+	sort.Slice(divergences, func(i, j int) bool {
+		if divergences[i].Snippet != divergences[j].Snippet {
+			return divergences[i].Snippet < divergences[j].Snippet
+		}
+
+		return divergences[i].Repository < divergences[j].Repository
+	})
+
+	return divergences
+}
+
+// repositoryCache fetches each repository only once, sharing it among members
+type repositoryCache struct {
+	factory      RepositoryFactory
+	mutex        sync.Mutex
+	repositories map[string]*cachedSource
+}
+
+// cachedSource is a repository fetched once, so concurrent jobs fetching
+// different repositories do not wait for each other
+type cachedSource struct {
+	once   sync.Once
+	source Source
+	err    error
+}
+
+// get implements RepositoryFactory
+func (cache *repositoryCache) get(options FetchOptions) (Source, error) {
+	cache.mutex.Lock()
+	repository, exists := cache.repositories[options.URL]
+	if !exists {
+		repository = &cachedSource{}
+		cache.repositories[options.URL] = repository
+	}
+	cache.mutex.Unlock()
+
+	repository.once.Do(func() {
+		repository.source, repository.err = cache.factory(options)
+	})
+
+	return repository.source, repository.err
+}
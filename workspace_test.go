@@ -0,0 +1,145 @@
+package maker_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wwmoraes/maker"
+)
+
+func writeTestFile(t *testing.T, filename, contents string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(filename), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filename, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func memberConfig(pin string) string {
+	return "repositories:\n- url: https://example.com/snippets.git\n  snippets:\n    go: '" + pin + "'\n"
+}
+
+func TestWorkspaceDivergences(t *testing.T) {
+	root := t.TempDir()
+
+	writeTestFile(t, filepath.Join(root, maker.WorkspaceFilename), "members:\n- a\n- b\n")
+	writeTestFile(t, filepath.Join(root, "a", maker.ConfFilename), memberConfig("=1.0.0"))
+	writeTestFile(t, filepath.Join(root, "b", maker.ConfFilename), memberConfig("^1"))
+
+	ws, err := maker.OpenWorkspace(root, maker.WithRepositoryFactory(snippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+		"1.1.0": "one dot one\n",
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	err = ws.Install(false, maker.StrategyMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	divergences := ws.Divergences()
+	if len(divergences) != 1 {
+		t.Fatalf("got %d divergences, want 1", len(divergences))
+	}
+
+	want := map[string]string{"a": "1.0.0", "b": "1.1.0"}
+	for dir, revision := range want {
+		if divergences[0].Revisions[dir] != revision {
+			t.Errorf("got member %s revision %s, want %s", dir, divergences[0].Revisions[dir], revision)
+		}
+	}
+}
+
+func TestWorkspaceSharedLock(t *testing.T) {
+	root := t.TempDir()
+
+	writeTestFile(t, filepath.Join(root, maker.WorkspaceFilename), "members:\n- a\n- b\nsharedLock: true\n")
+	writeTestFile(t, filepath.Join(root, "a", maker.ConfFilename), memberConfig("=1.0.0"))
+	writeTestFile(t, filepath.Join(root, "b", maker.ConfFilename), memberConfig("^1"))
+
+	factory := maker.WithRepositoryFactory(snippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+	}))
+
+	_, err := maker.OpenWorkspace(root, factory)
+	if err == nil {
+		t.Fatal("got no error for divergent pins on a shared lock")
+	}
+
+	writeTestFile(t, filepath.Join(root, "b", maker.ConfFilename), memberConfig("=1.0.0"))
+
+	ws, err := maker.OpenWorkspace(root, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	err = ws.Install(false, maker.StrategyMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{
+		filepath.Join(root, maker.LockFilename),
+		filepath.Join(root, "a", maker.SnippetsDirectory, "go.mk"),
+		filepath.Join(root, "b", maker.SnippetsDirectory, "go.mk"),
+	} {
+		_, err = os.Stat(filename)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(root, "a", maker.LockFilename))
+	if !os.IsNotExist(err) {
+		t.Errorf("got member lock file, want none with a shared lock")
+	}
+}
+
+func TestWorkspaceSharedLockVerify(t *testing.T) {
+	root := t.TempDir()
+
+	writeTestFile(t, filepath.Join(root, maker.WorkspaceFilename), "members:\n- a\n- b\nsharedLock: true\n")
+	writeTestFile(t, filepath.Join(root, "a", maker.ConfFilename), memberConfig("=1.0.0"))
+	writeTestFile(t, filepath.Join(root, "b", maker.ConfFilename), "repositories:\n- url: https://example.com/snippets.git\n")
+
+	ws, err := maker.OpenWorkspace(root, maker.WithRepositoryFactory(snippetsRepository(t, map[string]string{
+		"1.0.0": "one\n",
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	err = ws.Install(false, maker.StrategyMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"a", "b"} {
+		mk, found := ws.Member(dir)
+		if !found {
+			t.Fatalf("member %s not found", dir)
+		}
+
+		// b has no snippets, so the go entry of a is not missing from it
+		verification, err := mk.Verify()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !verification.OK() {
+			t.Errorf("member %s got verification %+v, want no differences", dir, verification)
+		}
+	}
+}